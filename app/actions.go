@@ -3,17 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/yeqown/go-qrcode"
 )
 
-func handleRedirect(db *pgxpool.Pool) http.HandlerFunc {
+func handleRedirect(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 		validPath, isPathValid := validateAndFormatPath(path)
@@ -21,16 +21,16 @@ func handleRedirect(db *pgxpool.Pool) http.HandlerFunc {
 			http.Error(w, notFoundMessage, http.StatusNotFound)
 			return
 		}
-		dbResponse, err := getRedirectUsingPath(validPath, db)
-		if err != nil || dbResponse.Id == 0 || (dbResponse.Id != 0 && dbResponse.Inactive) {
+		dbResponse, err := store.GetRedirectUsingPath(r.Context(), validPath)
+		if err != nil || dbResponse.Id == 0 || (dbResponse.Id != 0 && dbResponse.Inactive) || isRedirectExpired(dbResponse) {
 			http.Error(w, notFoundMessage, http.StatusNotFound)
 			return
 		}
-		http.Redirect(w, r, buildUri(dbResponse.Url), http.StatusFound)
+		http.Redirect(w, r, buildUri(dbResponse.Url), redirectStatus(dbResponse.StatusCode, 0))
 	})
 }
 
-func getRedirectQRCode(db *pgxpool.Pool) http.HandlerFunc {
+func getRedirectQRCode(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		shortPath := strings.ReplaceAll(r.URL.Path, "/qr", "")
 		validPath, isPathValid := validateAndFormatPath(shortPath)
@@ -38,8 +38,8 @@ func getRedirectQRCode(db *pgxpool.Pool) http.HandlerFunc {
 			http.Error(w, notFoundMessage, http.StatusNotFound)
 			return
 		}
-		dbResponse, err := getRedirectUsingPath(validPath, db)
-		if err != nil || dbResponse.Id == 0 || (dbResponse.Id != 0 && dbResponse.Inactive) {
+		dbResponse, err := store.GetRedirectUsingPath(r.Context(), validPath)
+		if err != nil || dbResponse.Id == 0 || (dbResponse.Id != 0 && dbResponse.Inactive) || isRedirectExpired(dbResponse) {
 			http.Error(w, notFoundMessage, http.StatusNotFound)
 			return
 		}
@@ -58,16 +58,21 @@ func getRedirectQRCode(db *pgxpool.Pool) http.HandlerFunc {
 	})
 }
 
-func redirectInfo(db *pgxpool.Pool) http.HandlerFunc {
+func redirectInfo(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		redirectId, idErr := strconv.Atoi(chi.URLParam(r, "id"))
 		if idErr != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		dbResponse, dbErr := store.GetRedirectUsingId(r.Context(), redirectId)
+		if dbErr != nil && !errors.Is(dbErr, errNotFound) {
+			log.Println("redirectInfo -> ", dbErr.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		dbResponse, dbErr := getRedirectUsingId(redirectId, db)
 		if dbErr != nil || dbResponse.Id != redirectId {
-			http.Error(w, dbError, http.StatusBadRequest)
+			writeApiError(w, r, http.StatusNotFound, notFoundCode, notExistMessage)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -75,116 +80,209 @@ func redirectInfo(db *pgxpool.Pool) http.HandlerFunc {
 	})
 }
 
-func addRedirect(db *pgxpool.Pool) http.HandlerFunc {
+func validateUrlData(requestData UrlData) (Redirect, error) {
+	validUrl, isUrlValid := validateAndFormatURL(requestData.Url)
+	canonicalUrl, isCanonicalUrlValid := canonicalizeUrl(requestData.Url)
+	validPath, pathErr := validateRedirectPath(requestData.Path)
+	switch {
+	case pathErr != nil:
+		return Redirect{}, pathErr
+	case !isUrlValid || !isCanonicalUrlValid:
+		return Redirect{}, &FieldError{Field: "url", Message: "must be an absolute URL"}
+	case !isRedirectStatusValid(requestData.StatusCode):
+		return Redirect{}, &FieldError{Field: "statusCode", Message: "must be 301, 302, 303, 307 or 308"}
+	case !isTitleValid(requestData.Title):
+		return Redirect{}, &FieldError{Field: "title", Message: "must be at most " + strconv.Itoa(maxTitleLength) + " characters"}
+	}
+	return Redirect{Path: validPath, Url: validUrl, CanonicalUrl: canonicalUrl, Tags: normalizeTags(requestData.Tags), StatusCode: redirectStatus(requestData.StatusCode, 0), Title: mergeTitle(requestData.Title, "")}, nil
+}
+
+func lockUniqueUrl(ctx context.Context, redirectStore RedirectStore, canonicalUrl string, allowDuplicate bool) error {
+	_, urlErr := redirectStore.LockCanonicalUrl(ctx, canonicalUrl)
+	switch {
+	case urlErr == nil && !allowDuplicate:
+		return errDuplicateUrl
+	case urlErr != nil && !errors.Is(urlErr, errNotFound):
+		return urlErr
+	}
+	return nil
+}
+
+func addRedirect(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var requestData UrlData
 		err := json.NewDecoder(r.Body).Decode(&requestData)
-		validUrl, isUrlValid := validateAndFormatURL(requestData.Url)
-		validPath, isPathValid := validateAndFormatPath(requestData.Path)
-		if !isUrlValid || !isPathValid || err != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+		validRedirect, validationErr := validateUrlData(requestData)
+		if err != nil {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		if validationErr != nil {
+			writeFieldError(w, r, validationErr)
 			return
 		}
-		_, duplicateErr := getRedirectUsingPath(validPath, db)
-		urlExists := doesUrlExists(validUrl, db)
-		if urlExists || duplicateErr == nil {
-			http.Error(w, alreadyExistMessage, http.StatusPreconditionFailed)
+		if isPathReserved(validRedirect.Path) {
+			writeFieldError(w, r, &FieldError{Field: "path", Code: pathReservedCode, Message: reservedPathMessage})
 			return
 		}
 		var responseData Redirect
-		db_err := db.QueryRow(context.Background(), "INSERT INTO UrlRedirects (path, url, updated_at) VALUES ($1,$2,now()) RETURNING id, path, url, updated_at::TEXT, inactive", validPath, validUrl).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
-		if db_err != nil {
+		db_err := runRedirectMutation(r, store, func(redirectStore RedirectStore) (mutateErr error) {
+			if urlErr := lockUniqueUrl(r.Context(), redirectStore, validRedirect.CanonicalUrl, requestData.AllowDuplicate); urlErr != nil {
+				return urlErr
+			}
+			responseData, mutateErr = insertRedirectWithinQuota(r.Context(), redirectStore, validRedirect, callerKeyId(r))
+			return mutateErr
+		})
+		var quotaErr *QuotaError
+		switch {
+		case errors.As(db_err, &quotaErr):
+			writeQuotaError(w, r, quotaErr)
+			return
+		case errors.Is(db_err, errConflict):
+			writeApiError(w, r, http.StatusConflict, alreadyExistsCode, alreadyExistMessage)
+			return
+		case db_err != nil:
 			log.Println("addRedirect -> ", db_err.Error())
-			http.Error(w, dbError, http.StatusInternalServerError)
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(toJson(responseData))
+		writeRedirectMutation(w, r, Redirect{}, responseData, http.StatusOK)
 	})
 }
 
-func patchRedirect(db *pgxpool.Pool) http.HandlerFunc {
+func patchRedirect(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var requestData UrlData
 		err := json.NewDecoder(r.Body).Decode(&requestData)
-		validUrl, isUrlValid := validateAndFormatURL(requestData.Url)
-		validPath, isPathValid := validateAndFormatPath(requestData.Path)
+		validRedirect, validationErr := validateUrlData(requestData)
 		w.Header().Set("Content-Type", "application/json")
-		if !isUrlValid || !isPathValid || err != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+		if err != nil {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		if validationErr != nil {
+			writeFieldError(w, r, validationErr)
+			return
+		}
+		dbResponse, dbErr := store.GetRedirectUsingPath(r.Context(), validRedirect.Path)
+		if dbErr != nil && !errors.Is(dbErr, errNotFound) {
+			log.Println("patchRedirect -> ", dbErr.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		var responseData Redirect
-		dbResponse, dbErr := getRedirectUsingPath(validPath, db)
 		if dbErr != nil || dbResponse.Id == 0 || (dbResponse.Id != 0 && dbResponse.Inactive) {
-			http.Error(w, notExistMessage, http.StatusPreconditionFailed)
+			writeApiError(w, r, http.StatusNotFound, notFoundCode, notExistMessage)
+			return
+		}
+		var responseData Redirect
+		db_err := runRedirectMutation(r, store, func(redirectStore RedirectStore) (mutateErr error) {
+			if validRedirect.CanonicalUrl != dbResponse.CanonicalUrl {
+				if urlErr := lockUniqueUrl(r.Context(), redirectStore, validRedirect.CanonicalUrl, requestData.AllowDuplicate); urlErr != nil {
+					return urlErr
+				}
+			}
+			responseData, mutateErr = redirectStore.UpdateRedirect(r.Context(), Redirect{Id: dbResponse.Id, Path: dbResponse.Path, Url: validRedirect.Url, CanonicalUrl: validRedirect.CanonicalUrl, Tags: mergeTags(requestData.Tags, dbResponse.Tags), StatusCode: redirectStatus(requestData.StatusCode, dbResponse.StatusCode), Title: mergeTitle(requestData.Title, dbResponse.Title), Folder: dbResponse.Folder, ExpiresAt: dbResponse.ExpiresAt, Inactive: false})
+			return mutateErr
+		})
+		if errors.Is(db_err, errConflict) {
+			writeApiError(w, r, http.StatusConflict, alreadyExistsCode, alreadyExistMessage)
 			return
 		}
-		db_err := db.QueryRow(context.Background(), "UPDATE UrlRedirects SET url=$1, updated_at=now(), inactive=$2 WHERE id=$3 RETURNING id, path, url, updated_at::TEXT, inactive", validUrl, false, dbResponse.Id).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
 		if db_err != nil {
 			log.Println("patchRedirect -> ", db_err.Error())
-			http.Error(w, dbError, http.StatusInternalServerError)
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(toJson(responseData))
+		writeRedirectMutation(w, r, dbResponse, responseData, http.StatusOK)
 	})
 }
 
-func updateRedirect(db *pgxpool.Pool) http.HandlerFunc {
+func updateRedirect(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var requestData Redirect
 		redirectId, idErr := strconv.Atoi(chi.URLParam(r, "id"))
 		if idErr != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
 			return
 		}
 		err := json.NewDecoder(r.Body).Decode(&requestData)
-		validUrl, isUrlValid := validateAndFormatURL(requestData.Url)
-		validPath, isPathValid := validateAndFormatPath(requestData.Path)
+		validRedirect, validationErr := validateUrlData(UrlData{Url: requestData.Url, Path: requestData.Path, StatusCode: requestData.StatusCode, Title: requestData.Title})
 		w.Header().Set("Content-Type", "application/json")
-		if !isUrlValid || !isPathValid || err != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+		if err != nil {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		if validationErr != nil {
+			writeFieldError(w, r, validationErr)
+			return
+		}
+		if isPathReserved(validRedirect.Path) {
+			writeFieldError(w, r, &FieldError{Field: "path", Code: pathReservedCode, Message: reservedPathMessage})
+			return
+		}
+		dbResponse, dbErr := store.GetRedirectUsingId(r.Context(), redirectId)
+		if dbErr != nil && !errors.Is(dbErr, errNotFound) {
+			log.Println("updateRedirect -> ", dbErr.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		var responseData Redirect
-		dbResponse, dbErr := getRedirectUsingId(redirectId, db)
 		if dbErr != nil || dbResponse.Id != redirectId {
-			http.Error(w, notExistMessage, http.StatusPreconditionFailed)
+			writeApiError(w, r, http.StatusNotFound, notFoundCode, notExistMessage)
+			return
+		}
+		allowDuplicate, _ := strconv.ParseBool(r.URL.Query().Get("allowDuplicate"))
+		var responseData Redirect
+		db_err := runRedirectMutation(r, store, func(redirectStore RedirectStore) (mutateErr error) {
+			if validRedirect.CanonicalUrl != dbResponse.CanonicalUrl {
+				if urlErr := lockUniqueUrl(r.Context(), redirectStore, validRedirect.CanonicalUrl, allowDuplicate); urlErr != nil {
+					return urlErr
+				}
+			}
+			responseData, mutateErr = redirectStore.UpdateRedirect(r.Context(), Redirect{Id: dbResponse.Id, Path: validRedirect.Path, Url: validRedirect.Url, CanonicalUrl: validRedirect.CanonicalUrl, Tags: mergeTags(requestData.Tags, dbResponse.Tags), StatusCode: redirectStatus(requestData.StatusCode, dbResponse.StatusCode), Title: mergeTitle(requestData.Title, dbResponse.Title), Folder: dbResponse.Folder, ExpiresAt: dbResponse.ExpiresAt, Inactive: false})
+			return mutateErr
+		})
+		if errors.Is(db_err, errConflict) {
+			writeApiError(w, r, http.StatusConflict, alreadyExistsCode, alreadyExistMessage)
 			return
 		}
-		db_err := db.QueryRow(context.Background(), "UPDATE UrlRedirects SET path=$1, url=$2, updated_at=now(), inactive=$3 WHERE id=$4 RETURNING id, path, url, updated_at::TEXT, inactive", validPath, validUrl, false, dbResponse.Id).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
 		if db_err != nil {
 			log.Println("updateRedirect -> ", db_err.Error())
-			http.Error(w, dbError, http.StatusInternalServerError)
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(toJson(responseData))
+		writeRedirectMutation(w, r, dbResponse, responseData, http.StatusOK)
 	})
 }
 
-func deleteRedirect(db *pgxpool.Pool) http.HandlerFunc {
+func deleteRedirect(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		redirectId, idErr := strconv.Atoi(chi.URLParam(r, "id"))
 		if idErr != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		dbResponse, dbErr := store.GetRedirectUsingId(r.Context(), redirectId)
+		if dbErr != nil && !errors.Is(dbErr, errNotFound) {
+			log.Println("deleteRedirect -> ", dbErr.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		var responseData Redirect
-		dbResponse, dbErr := getRedirectUsingId(redirectId, db)
 		if dbErr != nil || dbResponse.Id != redirectId {
-			http.Error(w, notExistMessage, http.StatusPreconditionFailed)
+			writeApiError(w, r, http.StatusNotFound, notFoundCode, notExistMessage)
 			return
 		}
-		db_err := db.QueryRow(context.Background(), "UPDATE UrlRedirects SET inactive=$1, updated_at=now() WHERE id=$2 RETURNING id, path, url, updated_at::TEXT, inactive", true, dbResponse.Id).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
+		disabledRedirect := dbResponse
+		disabledRedirect.Inactive = true
+		var responseData Redirect
+		db_err := runRedirectMutation(r, store, func(redirectStore RedirectStore) (mutateErr error) {
+			responseData, mutateErr = redirectStore.UpdateRedirect(r.Context(), disabledRedirect)
+			return mutateErr
+		})
 		if db_err != nil {
 			log.Println("deleteRedirect -> ", db_err.Error())
-			http.Error(w, dbError, http.StatusInternalServerError)
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(toJson(responseData))
+		writeRedirectMutation(w, r, dbResponse, responseData, http.StatusOK)
 	})
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const badRequestCode = "bad_request"
+const unauthorizedCode = "unauthorized"
+const insufficientScopeCode = "insufficient_scope"
+const notFoundCode = "not_found"
+const apiKeyNotFoundCode = "api_key_not_found"
+const alreadyExistsCode = "already_exists"
+const generateConflictCode = "generate_conflict"
+const pathReservedCode = "path_reserved"
+const preconditionFailedCode = "precondition_failed"
+const requestTooLargeCode = "request_too_large"
+const quotaExceededCode = "quota_exceeded"
+const rateLimitedCode = "rate_limited"
+const databaseErrorCode = "database_error"
+const internalErrorCode = "internal_error"
+const invalidFieldCode = "invalid_field"
+
+var apiErrorCodes = []string{badRequestCode, unauthorizedCode, insufficientScopeCode, notFoundCode, apiKeyNotFoundCode, alreadyExistsCode, generateConflictCode, pathReservedCode, preconditionFailedCode, requestTooLargeCode, quotaExceededCode, rateLimitedCode, databaseErrorCode, internalErrorCode, invalidFieldCode}
+
+type ApiError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Field     string `json:"field,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
+func writeApiErrorBody(w http.ResponseWriter, r *http.Request, statusCode int, apiError ApiError) {
+	apiError.RequestId = middleware.GetReqID(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	w.Write(toJson(apiError))
+}
+
+func writeApiError(w http.ResponseWriter, r *http.Request, statusCode int, code string, message string) {
+	writeApiErrorBody(w, r, statusCode, ApiError{Code: code, Message: message})
+}
+
+func writeFieldError(w http.ResponseWriter, r *http.Request, err error) {
+	apiError := ApiError{Code: invalidFieldCode, Message: err.Error()}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		apiError.Field, apiError.Message = fieldErr.Field, fieldErr.Message
+		if len(fieldErr.Code) > 0 {
+			apiError.Code = fieldErr.Code
+		}
+	}
+	writeApiErrorBody(w, r, http.StatusUnprocessableEntity, apiError)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type contextKey string
+
+const apiCallerContextKey contextKey = "apiCaller"
+
+const apiKeyTokenPrefix = "urk_"
+const envApiKeyId = "env"
+
+const readScope = "read"
+const writeScope = "write"
+const statsScope = "stats"
+const adminScope = "admin"
+
+var apiKeyScopes = []string{readScope, writeScope, statsScope, adminScope}
+
+type ApiKey struct {
+	Id           int      `json:"id,omitempty"`
+	KeyId        string   `json:"keyId,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	CreatedAt    string   `json:"createdAt,omitempty"`
+	ExpiresAt    string   `json:"expiresAt,omitempty"`
+	LastUsedAt   string   `json:"lastUsedAt,omitempty"`
+	Revoked      bool     `json:"revoked,omitempty"`
+	RateLimit    int      `json:"rateLimit,omitempty"`
+	DailyQuota   int      `json:"dailyQuota,omitempty"`
+	MonthlyQuota int      `json:"monthlyQuota,omitempty"`
+	Token        string   `json:"token,omitempty"`
+}
+
+type ApiKeyData struct {
+	Name         string   `json:"name,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	ExpiresAt    int64    `json:"expiresAt,omitempty"`
+	RateLimit    int      `json:"rateLimit,omitempty"`
+	DailyQuota   int      `json:"dailyQuota,omitempty"`
+	MonthlyQuota int      `json:"monthlyQuota,omitempty"`
+}
+
+type ApiCaller struct {
+	KeyId        string
+	Scopes       []string
+	RateLimit    int
+	DailyQuota   int
+	MonthlyQuota int
+}
+
+func (caller *ApiCaller) hasScope(scope string) bool {
+	return slices.Contains(caller.Scopes, adminScope) || slices.Contains(caller.Scopes, scope)
+}
+
+func apiCallerFromContext(ctx context.Context) *ApiCaller {
+	caller, _ := ctx.Value(apiCallerContextKey).(*ApiCaller)
+	return caller
+}
+
+func withApiCaller(r *http.Request, caller ApiCaller) *http.Request {
+	if existingCaller := apiCallerFromContext(r.Context()); existingCaller != nil {
+		*existingCaller = caller
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), apiCallerContextKey, &caller))
+}
+
+func callerKeyId(r *http.Request) string {
+	if caller := apiCallerFromContext(r.Context()); caller != nil {
+		return caller.KeyId
+	}
+	return ""
+}
+
+func validateApiKeyScopes(scopes []string) bool {
+	if len(scopes) == 0 {
+		return false
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func randomToken(size int) string {
+	tokenBytes := make([]byte, size)
+	rand.Read(tokenBytes)
+	return base64.RawURLEncoding.EncodeToString(tokenBytes)
+}
+
+func hashApiKeySecret(secret string) string {
+	secretHash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(secretHash[:])
+}
+
+func generateApiKeyToken() (string, string, string) {
+	keyIdBytes := make([]byte, 6)
+	rand.Read(keyIdBytes)
+	keyId := hex.EncodeToString(keyIdBytes)
+	secret := randomToken(32)
+	return keyId, secret, apiKeyTokenPrefix + keyId + "." + secret
+}
+
+func parseApiKeyToken(token string) (string, string, bool) {
+	keyToken, hasPrefix := strings.CutPrefix(token, apiKeyTokenPrefix)
+	if !hasPrefix {
+		return "", "", false
+	}
+	keyId, secret, found := strings.Cut(keyToken, ".")
+	return keyId, secret, found && len(keyId) > 0 && len(secret) > 0
+}
+
+func authenticateApiKey(ctx context.Context, token string, store ApiKeyStore) (ApiCaller, bool) {
+	if len(apiKey) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) == 1 {
+		return ApiCaller{KeyId: envApiKeyId, Scopes: []string{adminScope}}, true
+	}
+	keyId, secret, isTokenValid := parseApiKeyToken(token)
+	if !isTokenValid {
+		return ApiCaller{}, false
+	}
+	caller, secretHash, db_err := store.GetApiKeyCaller(ctx, keyId)
+	if db_err != nil || subtle.ConstantTimeCompare([]byte(hashApiKeySecret(secret)), []byte(secretHash)) != 1 {
+		return ApiCaller{}, false
+	}
+	updateErr := store.TouchApiKey(ctx, keyId)
+	if updateErr != nil {
+		log.Println("authenticateApiKey -> ", updateErr.Error())
+	}
+	return caller, true
+}
+
+func createApiKey(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestData ApiKeyData
+		err := json.NewDecoder(r.Body).Decode(&requestData)
+		name := strings.TrimSpace(requestData.Name)
+		isLimitValid := requestData.RateLimit >= 0 && requestData.DailyQuota >= 0 && requestData.MonthlyQuota >= 0
+		if err != nil || len(name) == 0 || !validateApiKeyScopes(requestData.Scopes) || !isLimitValid {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		requestData.Name = name
+		keyId, secret, token := generateApiKeyToken()
+		responseData, db_err := store.InsertApiKey(r.Context(), keyId, hashApiKeySecret(secret), requestData)
+		if db_err != nil {
+			log.Println("createApiKey -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		auditState(r, nil, responseData)
+		responseData.Token = token
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(toJson(responseData))
+	})
+}
+
+func listApiKeys(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responseData, db_err := store.ListApiKeys(r.Context())
+		if db_err != nil {
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(toJson(responseData))
+	})
+}
+
+func revokeApiKey(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyId := chi.URLParam(r, "keyId")
+		responseData, db_err := store.RevokeApiKey(r.Context(), keyId)
+		if errors.Is(db_err, errNotFound) {
+			writeApiError(w, r, http.StatusNotFound, apiKeyNotFoundCode, apiKeyNotExistMessage)
+			return
+		}
+		if db_err != nil {
+			log.Println("revokeApiKey -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		auditState(r, nil, responseData)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(toJson(responseData))
+	})
+}
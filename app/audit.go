@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const auditContextKey contextKey = "auditEntry"
+const auditPageLimit = 100
+const anonymousActor = "anonymous"
+
+const auditSuccess = "success"
+const auditFailure = "failure"
+const auditUnauthorized = "unauthorized"
+const auditForbidden = "forbidden"
+
+type AuditEntry struct {
+	Before any
+	After  any
+}
+
+type AuditLog struct {
+	Id           int64           `json:"id,omitempty"`
+	Timestamp    string          `json:"timestamp,omitempty"`
+	Actor        string          `json:"actor,omitempty"`
+	SourceIp     string          `json:"sourceIp,omitempty"`
+	RequestId    string          `json:"requestId,omitempty"`
+	Method       string          `json:"method,omitempty"`
+	Action       string          `json:"action,omitempty"`
+	RedirectId   int             `json:"redirectId,omitempty"`
+	RedirectPath string          `json:"redirectPath,omitempty"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	Status       int             `json:"status,omitempty"`
+	Result       string          `json:"result,omitempty"`
+}
+
+type AuditQuery struct {
+	Start int64  `json:"start,omitempty"`
+	End   int64  `json:"end,omitempty"`
+	Path  string `json:"path,omitempty"`
+	Actor string `json:"actor,omitempty"`
+}
+
+func auditState(r *http.Request, before any, after any) {
+	if entry, isAudited := r.Context().Value(auditContextKey).(*AuditEntry); isAudited {
+		entry.Before, entry.After = before, after
+	}
+}
+
+func auditResult(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return auditUnauthorized
+	case status == http.StatusForbidden:
+		return auditForbidden
+	case status >= http.StatusBadRequest:
+		return auditFailure
+	default:
+		return auditSuccess
+	}
+}
+
+func auditActor(r *http.Request) string {
+	if caller := apiCallerFromContext(r.Context()); caller != nil && len(caller.KeyId) > 0 {
+		return caller.KeyId
+	}
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") && isJwtAuthEnabled() {
+		return jwtAuthMode
+	}
+	if keyId, _, isTokenValid := parseApiKeyToken(r.Header.Get("x-url-redirect-token")); isTokenValid {
+		return keyId
+	}
+	return anonymousActor
+}
+
+func auditJson(state any) []byte {
+	if state == nil {
+		return nil
+	}
+	return toJson(state)
+}
+
+func auditRedirect(entry *AuditEntry) (int, string) {
+	for _, state := range []any{entry.After, entry.Before} {
+		if redirect, isRedirect := state.(Redirect); isRedirect && redirect.Id > 0 {
+			return redirect.Id, redirect.Path
+		}
+	}
+	return 0, ""
+}
+
+func recordAudit(store AuditStore, r *http.Request, entry *AuditEntry, status int) {
+	redirectId, redirectPath := auditRedirect(entry)
+	db_err := store.InsertAuditLog(context.Background(), AuditLog{
+		Actor:        auditActor(r),
+		SourceIp:     clientIp(r),
+		RequestId:    middleware.GetReqID(r.Context()),
+		Method:       r.Method,
+		Action:       r.URL.Path,
+		RedirectId:   redirectId,
+		RedirectPath: redirectPath,
+		Before:       auditJson(entry.Before),
+		After:        auditJson(entry.After),
+		Status:       status,
+		Result:       auditResult(status),
+	})
+	if db_err != nil {
+		log.Println("Audit Insert Error:", db_err)
+	}
+}
+
+func auditAction(store Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entry := &AuditEntry{}
+			appResponse := &AppResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			r = r.WithContext(context.WithValue(r.Context(), auditContextKey, entry))
+			next.ServeHTTP(appResponse, r)
+			recordAudit(store, r, entry, appResponse.Status())
+		})
+	}
+}
+
+func auditLogs(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var auditQuery AuditQuery
+		err := json.NewDecoder(r.Body).Decode(&auditQuery)
+		if err != nil {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		if auditQuery.End <= 0 {
+			auditQuery.End = time.Now().Unix()
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		responseData, db_err := store.ListAuditLogs(r.Context(), auditQuery, auditPageLimit, max(page, 0)*auditPageLimit)
+		if db_err != nil {
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(toJson(responseData))
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const batchDisable = "disable"
+const batchEnable = "enable"
+const batchTag = "tag"
+const batchMove = "move"
+const batchExpire = "expire"
+const batchStatus = "status"
+
+const batchUpdated = "updated"
+const batchUnchanged = "unchanged"
+const batchFailed = "failed"
+const batchRolledBack = "rolledBack"
+
+const maxBatchSize = 1000
+
+var batchActions = []string{batchDisable, batchEnable, batchTag, batchMove, batchExpire, batchStatus}
+var batchFilterParams = []string{"status", "tag", "domain", "updatedSince"}
+
+var errBatchFailed = errors.New("batch failed")
+var errBatchTooLarge = errors.New("batch matches too many redirects")
+
+type BatchRequest struct {
+	Action     string   `json:"action"`
+	Ids        []int    `json:"ids,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Folder     string   `json:"folder,omitempty"`
+	ExpiresAt  int64    `json:"expiresAt,omitempty"`
+	StatusCode int      `json:"statusCode,omitempty"`
+}
+
+type BatchResult struct {
+	Id     int    `json:"id"`
+	Path   string `json:"path,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchReport struct {
+	Action     string        `json:"action"`
+	DryRun     bool          `json:"dryRun,omitempty"`
+	Total      int           `json:"total"`
+	Updated    int           `json:"updated"`
+	Unchanged  int           `json:"unchanged"`
+	Failed     int           `json:"failed"`
+	RolledBack bool          `json:"rolledBack,omitempty"`
+	Results    []BatchResult `json:"results,omitempty"`
+}
+
+func (report *BatchReport) add(result BatchResult) {
+	report.Total++
+	switch result.Status {
+	case batchUpdated:
+		report.Updated++
+	case batchUnchanged:
+		report.Unchanged++
+	default:
+		report.Failed++
+	}
+	report.Results = append(report.Results, result)
+}
+
+func (report *BatchReport) rollback() {
+	report.RolledBack = true
+	report.Updated = 0
+	for i, result := range report.Results {
+		if result.Status == batchUpdated {
+			report.Results[i].Status = batchRolledBack
+		}
+	}
+}
+
+func validateBatchRequest(batchRequest BatchRequest) (BatchRequest, bool) {
+	var isFolderValid bool
+	batchRequest.Action = strings.ToLower(strings.TrimSpace(batchRequest.Action))
+	batchRequest.Tags = normalizeTags(batchRequest.Tags)
+	batchRequest.Folder, isFolderValid = normalizeFolder(batchRequest.Folder)
+	slices.Sort(batchRequest.Ids)
+	batchRequest.Ids = slices.Compact(batchRequest.Ids)
+	switch {
+	case !slices.Contains(batchActions, batchRequest.Action) || len(batchRequest.Ids) > maxBatchSize:
+		return batchRequest, false
+	case len(batchRequest.Ids) > 0 && batchRequest.Ids[0] <= 0:
+		return batchRequest, false
+	case batchRequest.Action == batchTag && len(batchRequest.Tags) == 0:
+		return batchRequest, false
+	case batchRequest.Action == batchMove && !isFolderValid:
+		return batchRequest, false
+	case batchRequest.Action == batchExpire && batchRequest.ExpiresAt < 0:
+		return batchRequest, false
+	case batchRequest.Action == batchStatus && (batchRequest.StatusCode == 0 || !isRedirectStatusValid(batchRequest.StatusCode)):
+		return batchRequest, false
+	}
+	return batchRequest, true
+}
+
+func applyBatchAction(redirect Redirect, batchRequest BatchRequest) Redirect {
+	switch batchRequest.Action {
+	case batchDisable:
+		redirect.Inactive = true
+	case batchEnable:
+		redirect.Inactive = false
+	case batchTag:
+		redirect.Tags = normalizeTags(append(slices.Clone(redirect.Tags), batchRequest.Tags...))
+	case batchMove:
+		redirect.Folder = batchRequest.Folder
+	case batchExpire:
+		redirect.ExpiresAt = ""
+		if batchRequest.ExpiresAt > 0 {
+			redirect.ExpiresAt = time.Unix(batchRequest.ExpiresAt, 0).UTC().Format(time.DateTime)
+		}
+	case batchStatus:
+		redirect.StatusCode = batchRequest.StatusCode
+	}
+	return redirect
+}
+
+func batchRedirect(ctx context.Context, store RedirectStore, redirect Redirect, batchRequest BatchRequest) BatchResult {
+	result := BatchResult{Id: redirect.Id, Path: redirect.Path, Status: batchUnchanged}
+	updatedRedirect := applyBatchAction(redirect, batchRequest)
+	if len(redirectChanges(redirect, updatedRedirect)) == 0 {
+		return result
+	}
+	if _, db_err := store.UpdateRedirect(ctx, updatedRedirect); db_err != nil {
+		log.Println("batchRedirect -> ", db_err.Error())
+		result.Status, result.Error = batchFailed, dbError
+		return result
+	}
+	result.Status = batchUpdated
+	return result
+}
+
+func batchTargets(ctx context.Context, store RedirectStore, batchRequest BatchRequest, filter RedirectFilter, report *BatchReport) ([]Redirect, error) {
+	if len(batchRequest.Ids) == 0 {
+		redirects, db_err := store.ListRedirects(ctx, RedirectQuery{RedirectFilter: filter, Limit: maxBatchSize + 1})
+		if len(redirects) > maxBatchSize {
+			return nil, errBatchTooLarge
+		}
+		return redirects, db_err
+	}
+	var redirects []Redirect
+	for _, redirectId := range batchRequest.Ids {
+		redirect, db_err := store.GetRedirectUsingId(ctx, redirectId)
+		switch {
+		case errors.Is(db_err, errNotFound):
+			report.add(BatchResult{Id: redirectId, Status: batchFailed, Error: notExistMessage})
+		case db_err != nil:
+			return nil, db_err
+		default:
+			redirects = append(redirects, redirect)
+		}
+	}
+	return redirects, nil
+}
+
+func batchRedirects(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestData BatchRequest
+		err := json.NewDecoder(r.Body).Decode(&requestData)
+		batchRequest, isBatchValid := validateBatchRequest(requestData)
+		filter, isFilterValid := parseRedirectFilter(r)
+		hasFilter := slices.ContainsFunc(batchFilterParams, r.URL.Query().Has)
+		dryRun := isDryRun(r)
+		if err != nil || !isBatchValid || !isFilterValid || (len(batchRequest.Ids) == 0 && !hasFilter) || (len(batchRequest.Ids) > 0 && hasFilter) {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		report := BatchReport{Action: batchRequest.Action, DryRun: dryRun}
+		db_err := store.RunInTransaction(r.Context(), func(redirectStore RedirectStore) error {
+			redirects, db_err := batchTargets(r.Context(), redirectStore, batchRequest, filter, &report)
+			if db_err != nil {
+				return db_err
+			}
+			for _, redirect := range redirects {
+				report.add(batchRedirect(r.Context(), redirectStore, redirect, batchRequest))
+			}
+			if report.Failed > 0 {
+				return errBatchFailed
+			}
+			if dryRun {
+				return errDryRun
+			}
+			return nil
+		})
+		responseStatus := http.StatusOK
+		switch {
+		case errors.Is(db_err, errBatchFailed):
+			report.rollback()
+			responseStatus = http.StatusUnprocessableEntity
+		case errors.Is(db_err, errDryRun):
+		case errors.Is(db_err, errBatchTooLarge):
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		case db_err != nil:
+			log.Println("batchRedirects -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		auditSummary := report
+		auditSummary.Results = nil
+		auditState(r, nil, auditSummary)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(responseStatus)
+		w.Write(toJson(report))
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const yamlFormat = "yaml"
+const exportBatchSize = 500
+
+var exportFormats = []string{csvFormat, jsonFormat, ndjsonFormat, yamlFormat}
+var exportContentTypes = map[string]string{
+	csvFormat:    "text/csv",
+	jsonFormat:   "application/json",
+	ndjsonFormat: "application/x-ndjson",
+	yamlFormat:   "application/yaml",
+}
+var exportCsvHeader = []string{"id", "path", "url", "canonicalUrl", "inactive", "tags", "statusCode", "title", "lastUpdated"}
+
+type RedirectExporter struct {
+	w         io.Writer
+	format    string
+	csvWriter *csv.Writer
+	count     int
+}
+
+func parseRedirectFilter(r *http.Request) (RedirectFilter, bool) {
+	queryParams := r.URL.Query()
+	filter := RedirectFilter{Tag: strings.ToLower(strings.TrimSpace(queryParams.Get("tag")))}
+	if domain := strings.TrimSpace(queryParams.Get("domain")); len(domain) > 0 {
+		canonicalDomain, isDomainValid := canonicalizeUrl(domain)
+		if !isDomainValid {
+			return filter, false
+		}
+		filter.Domain, _, _ = strings.Cut(canonicalDomain, "/")
+	}
+	status := strings.ToLower(strings.TrimSpace(queryParams.Get("status")))
+	switch status {
+	case "", "all":
+	case "active", "inactive":
+		isInactive := status == "inactive"
+		filter.Inactive = &isInactive
+	default:
+		return filter, false
+	}
+	if updatedSince := queryParams.Get("updatedSince"); len(updatedSince) > 0 {
+		unixTime, parseErr := strconv.ParseInt(updatedSince, 10, 64)
+		if parseErr == nil {
+			filter.UpdatedSince = time.Unix(unixTime, 0)
+		} else if filter.UpdatedSince, parseErr = time.Parse(time.RFC3339, updatedSince); parseErr != nil {
+			return filter, false
+		}
+	}
+	return filter, true
+}
+
+func newRedirectExporter(w io.Writer, format string) *RedirectExporter {
+	exporter := &RedirectExporter{w: w, format: format}
+	switch format {
+	case csvFormat:
+		exporter.csvWriter = csv.NewWriter(w)
+		exporter.csvWriter.Write(exportCsvHeader)
+	case jsonFormat:
+		io.WriteString(w, "[")
+	}
+	return exporter
+}
+
+func (exporter *RedirectExporter) write(redirect Redirect) error {
+	defer func() { exporter.count++ }()
+	switch exporter.format {
+	case csvFormat:
+		return exporter.csvWriter.Write([]string{strconv.Itoa(redirect.Id), redirect.Path, redirect.Url, redirect.CanonicalUrl, strconv.FormatBool(redirect.Inactive), strings.Join(redirect.Tags, ","), strconv.Itoa(redirect.StatusCode), redirect.Title, redirect.LastUpdated})
+	case ndjsonFormat:
+		_, err := fmt.Fprintf(exporter.w, "%s\n", toJson(redirect))
+		return err
+	case yamlFormat:
+		if redirect.Tags == nil {
+			redirect.Tags = []string{}
+		}
+		_, err := fmt.Fprintf(exporter.w, "- id: %d\n  path: %s\n  url: %s\n  canonicalUrl: %s\n  inactive: %t\n  tags: %s\n  statusCode: %d\n  title: %s\n  lastUpdated: %s\n",
+			redirect.Id, toJson(redirect.Path), toJson(redirect.Url), toJson(redirect.CanonicalUrl), redirect.Inactive, toJson(redirect.Tags), redirect.StatusCode, toJson(redirect.Title), toJson(redirect.LastUpdated))
+		return err
+	}
+	separator := ","
+	if exporter.count == 0 {
+		separator = ""
+	}
+	_, err := fmt.Fprintf(exporter.w, "%s\n%s", separator, toJson(redirect))
+	return err
+}
+
+func (exporter *RedirectExporter) flush() error {
+	if exporter.csvWriter != nil {
+		exporter.csvWriter.Flush()
+		return exporter.csvWriter.Error()
+	}
+	return nil
+}
+
+func (exporter *RedirectExporter) close() error {
+	switch {
+	case exporter.format == jsonFormat:
+		io.WriteString(exporter.w, "\n]\n")
+	case exporter.format == yamlFormat && exporter.count == 0:
+		io.WriteString(exporter.w, "[]\n")
+	}
+	return exporter.flush()
+}
+
+func exportRedirects(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exportFormat := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+		if len(exportFormat) == 0 {
+			exportFormat = jsonFormat
+		}
+		filter, isFilterValid := parseRedirectFilter(r)
+		if !isFilterValid || !slices.Contains(exportFormats, exportFormat) {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		redirects, db_err := store.ListRedirects(r.Context(), RedirectQuery{RedirectFilter: filter, Limit: exportBatchSize})
+		if db_err != nil {
+			log.Println("exportRedirects -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		responseController := http.NewResponseController(w)
+		responseController.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", exportContentTypes[exportFormat])
+		w.Header().Set("Content-Disposition", "attachment; filename=\"redirects."+exportFormat+"\"")
+		w.WriteHeader(http.StatusOK)
+		exporter := newRedirectExporter(w, exportFormat)
+		for len(redirects) > 0 {
+			for _, redirect := range redirects {
+				if writeErr := exporter.write(redirect); writeErr != nil {
+					log.Println("exportRedirects -> ", writeErr.Error())
+					return
+				}
+			}
+			exporter.flush()
+			responseController.Flush()
+			if len(redirects) < exportBatchSize {
+				break
+			}
+			redirects, db_err = store.ListRedirects(r.Context(), RedirectQuery{RedirectFilter: filter, AfterId: redirects[len(redirects)-1].Id, Limit: exportBatchSize})
+			if db_err != nil {
+				log.Println("exportRedirects -> ", db_err.Error())
+				panic(http.ErrAbortHandler)
+			}
+		}
+		exporter.close()
+	})
+}
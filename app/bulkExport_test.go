@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type failingPageStore struct {
+	*MemoryStore
+}
+
+func (store failingPageStore) ListRedirects(ctx context.Context, query RedirectQuery) ([]Redirect, error) {
+	if query.AfterId > 0 {
+		return nil, errors.New("connection reset")
+	}
+	return store.MemoryStore.ListRedirects(ctx, query)
+}
+
+func TestExportAbortsOnStreamError(t *testing.T) {
+	store := newMemoryStore()
+	for i := range exportBatchSize {
+		store.InsertRedirect(context.Background(), Redirect{Path: "path" + strconv.Itoa(i), Url: "example.com"}, "")
+	}
+	server := httptest.NewServer(exportRedirects(failingPageStore{store}))
+	defer server.Close()
+	response, requestErr := http.Get(server.URL + "?format=ndjson")
+	if requestErr != nil {
+		t.Fatal(requestErr)
+	}
+	defer response.Body.Close()
+	if _, readErr := io.ReadAll(response.Body); readErr == nil {
+		t.Error("export ended cleanly after a database error")
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const importSkipMode = "skip"
+const importUpdateMode = "update"
+const importAtomicMode = "atomic"
+
+const csvFormat = "csv"
+const jsonFormat = "json"
+const ndjsonFormat = "ndjson"
+
+const importCreated = "created"
+const importUpdated = "updated"
+const importSkipped = "skipped"
+const importFailed = "failed"
+const importRolledBack = "rolledBack"
+
+const importTimeout = 10 * time.Minute
+
+var importModes = []string{importSkipMode, importUpdateMode, importAtomicMode}
+var importFormats = []string{csvFormat, jsonFormat, ndjsonFormat, bitlyFormat, yourlsFormat, kuttFormat, apacheFormat}
+
+var errImportFailed = errors.New("import failed")
+var errImportFormat = errors.New("invalid import format")
+
+type ImportRow struct {
+	UrlData
+	CreatedAt   time.Time
+	Clicks      int64
+	Source      string
+	Unsupported string
+}
+
+type ImportResult struct {
+	Row         int                    `json:"row"`
+	Path        string                 `json:"path,omitempty"`
+	Id          int                    `json:"id,omitempty"`
+	Status      string                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	Source      string                 `json:"source,omitempty"`
+	ExistingUrl string                 `json:"existingUrl,omitempty"`
+	Changes     map[string]FieldChange `json:"changes,omitempty"`
+}
+
+type ImportReport struct {
+	Mode       string         `json:"mode"`
+	DryRun     bool           `json:"dryRun,omitempty"`
+	Total      int            `json:"total"`
+	Created    int            `json:"created"`
+	Updated    int            `json:"updated"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	RolledBack bool           `json:"rolledBack,omitempty"`
+	Results    []ImportResult `json:"results,omitempty"`
+}
+
+func (report *ImportReport) add(result ImportResult) {
+	report.Total++
+	result.Row = report.Total
+	switch result.Status {
+	case importCreated:
+		report.Created++
+	case importUpdated:
+		report.Updated++
+	case importSkipped:
+		report.Skipped++
+	default:
+		report.Failed++
+	}
+	report.Results = append(report.Results, result)
+}
+
+func (report *ImportReport) rollback() {
+	report.RolledBack = true
+	report.Created, report.Updated = 0, 0
+	for i, result := range report.Results {
+		if result.Status == importCreated || result.Status == importUpdated {
+			report.Results[i].Status = importRolledBack
+			report.Results[i].Id = 0
+		}
+	}
+}
+
+func (report *ImportReport) discard() {
+	for i, result := range report.Results {
+		if result.Status == importCreated {
+			report.Results[i].Id = 0
+		}
+	}
+}
+
+func getImportFormat(r *http.Request) string {
+	importFormat := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if len(importFormat) > 0 {
+		return importFormat
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return csvFormat
+	case "application/x-ndjson":
+		return ndjsonFormat
+	}
+	return jsonFormat
+}
+
+func readCsvRows(body io.Reader, handleRow func(ImportRow)) error {
+	csvReader := csv.NewReader(body)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	columns := map[string]int{"path": 0, "url": 1, "allowduplicate": -1, "tags": -1, "statuscode": -1, "title": -1}
+	for isFirstRecord := true; ; isFirstRecord = false {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isFirstRecord {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+			header := make([]string, len(record))
+			for i, column := range record {
+				header[i] = strings.ToLower(strings.TrimSpace(column))
+			}
+			if slices.Contains(header, "url") {
+				for column := range columns {
+					columns[column] = slices.Index(header, column)
+				}
+				continue
+			}
+		}
+		field := func(column string) string {
+			if index := columns[column]; index >= 0 && index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		allowDuplicate, _ := strconv.ParseBool(field("allowduplicate"))
+		statusCode, _ := strconv.Atoi(field("statuscode"))
+		requestData := UrlData{Path: field("path"), Url: field("url"), AllowDuplicate: allowDuplicate, StatusCode: statusCode, Title: field("title")}
+		if tags := field("tags"); len(tags) > 0 {
+			requestData.Tags = []string{tags}
+		}
+		handleRow(ImportRow{UrlData: requestData})
+	}
+}
+
+func readJsonRows(body io.Reader, handleRow func(ImportRow)) error {
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		return errImportFormat
+	}
+	for decoder.More() {
+		var requestData UrlData
+		if err := decoder.Decode(&requestData); err != nil {
+			return err
+		}
+		handleRow(ImportRow{UrlData: requestData})
+	}
+	_, err = decoder.Token()
+	return err
+}
+
+func readNdjsonRows(body io.Reader, handleRow func(ImportRow)) error {
+	decoder := json.NewDecoder(body)
+	for {
+		var requestData UrlData
+		err := decoder.Decode(&requestData)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		handleRow(ImportRow{UrlData: requestData})
+	}
+}
+
+func readImportRows(body io.Reader, importFormat string, handleRow func(ImportRow)) error {
+	switch importFormat {
+	case csvFormat:
+		return readCsvRows(body, handleRow)
+	case ndjsonFormat:
+		return readNdjsonRows(body, handleRow)
+	case apacheFormat:
+		return readApacheRules(body, handleRow)
+	case bitlyFormat, yourlsFormat, kuttFormat:
+		return readSourceRows(body, importSources[importFormat], handleRow)
+	}
+	return readJsonRows(body, handleRow)
+}
+
+func importRedirect(ctx context.Context, store RedirectStore, importMode string, importRow ImportRow, createdBy string) ImportResult {
+	if len(importRow.Unsupported) > 0 {
+		return ImportResult{Path: importRow.Path, Status: importFailed, Error: unsupportedRule + importRow.Unsupported, Source: importRow.Source}
+	}
+	requestData := importRow.UrlData
+	validRedirect, validationErr := validateUrlData(requestData)
+	if validationErr != nil {
+		return ImportResult{Path: requestData.Path, Status: importFailed, Error: validationErr.Error(), Source: importRow.Source}
+	}
+	if isPathReserved(validRedirect.Path) {
+		return ImportResult{Path: requestData.Path, Status: importFailed, Error: reservedPathMessage, Source: importRow.Source}
+	}
+	if !importRow.CreatedAt.IsZero() {
+		validRedirect.CreatedAt = formatTimestamp(importRow.CreatedAt)
+	}
+	validRedirect.ImportedClicks = importRow.Clicks
+	result := ImportResult{Path: validRedirect.Path}
+	dbResponse, pathErr := store.GetRedirectUsingPath(ctx, validRedirect.Path)
+	if pathErr == nil {
+		result.ExistingUrl = dbResponse.Url
+	}
+	switch {
+	case pathErr == nil && importMode == importSkipMode:
+		result.Id, result.Status = dbResponse.Id, importSkipped
+		return result
+	case pathErr == nil && importMode == importUpdateMode:
+		responseData, db_err := store.UpdateRedirect(ctx, Redirect{Id: dbResponse.Id, Path: dbResponse.Path, Url: validRedirect.Url, CanonicalUrl: validRedirect.CanonicalUrl, Tags: mergeTags(requestData.Tags, dbResponse.Tags), StatusCode: redirectStatus(requestData.StatusCode, dbResponse.StatusCode), Title: mergeTitle(requestData.Title, dbResponse.Title), Folder: dbResponse.Folder, ExpiresAt: dbResponse.ExpiresAt, Inactive: false})
+		if db_err != nil {
+			log.Println("importRedirect -> ", db_err.Error())
+			result.Status, result.Error = importFailed, dbError
+			return result
+		}
+		result.Id, result.Status, result.Changes = responseData.Id, importUpdated, redirectChanges(dbResponse, responseData)
+		return result
+	case pathErr == nil:
+		result.Status, result.Error = importFailed, alreadyExistMessage
+		return result
+	case !errors.Is(pathErr, errNotFound):
+		log.Println("importRedirect -> ", pathErr.Error())
+		result.Status, result.Error = importFailed, dbError
+		return result
+	}
+	urlErr := lockUniqueUrl(ctx, store, validRedirect.CanonicalUrl, requestData.AllowDuplicate)
+	switch {
+	case errors.Is(urlErr, errDuplicateUrl):
+		result.Status, result.Error = importFailed, alreadyExistMessage
+		return result
+	case urlErr != nil:
+		log.Println("importRedirect -> ", urlErr.Error())
+		result.Status, result.Error = importFailed, dbError
+		return result
+	}
+	responseData, db_err := insertRedirectWithinQuota(ctx, store, validRedirect, createdBy)
+	var quotaErr *QuotaError
+	switch {
+	case errors.As(db_err, &quotaErr):
+		result.Status, result.Error = importFailed, quotaExceededMessage
+	case errors.Is(db_err, errConflict):
+		result.Status, result.Error = importFailed, alreadyExistMessage
+	case db_err != nil:
+		log.Println("importRedirect -> ", db_err.Error())
+		result.Status, result.Error = importFailed, dbError
+	default:
+		result.Id, result.Status = responseData.Id, importCreated
+	}
+	return result
+}
+
+func importRedirects(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		importMode := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("mode")))
+		if len(importMode) == 0 {
+			importMode = importSkipMode
+		}
+		importFormat := getImportFormat(r)
+		allowDuplicate, _ := strconv.ParseBool(r.URL.Query().Get("allowDuplicate"))
+		dryRun := isDryRun(r)
+		if !slices.Contains(importModes, importMode) || !slices.Contains(importFormats, importFormat) {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		responseController := http.NewResponseController(w)
+		responseController.SetReadDeadline(time.Now().Add(importTimeout))
+		responseController.SetWriteDeadline(time.Now().Add(importTimeout))
+		requestBody := http.MaxBytesReader(w, r.Body, maxImportBodySize)
+		report := ImportReport{Mode: importMode, DryRun: dryRun}
+		createdBy := callerKeyId(r)
+		isTransaction := importMode == importAtomicMode || dryRun
+		runImport := func(redirectStore RedirectStore) error {
+			parseErr := readImportRows(requestBody, importFormat, func(importRow ImportRow) {
+				importRow.AllowDuplicate = importRow.AllowDuplicate || allowDuplicate
+				if isTransaction {
+					report.add(importRedirect(r.Context(), redirectStore, importMode, importRow, createdBy))
+					return
+				}
+				var result ImportResult
+				db_err := redirectStore.RunInTransaction(r.Context(), func(rowStore RedirectStore) error {
+					result = importRedirect(r.Context(), rowStore, importMode, importRow, createdBy)
+					return nil
+				})
+				if db_err != nil {
+					log.Println("importRedirects -> ", db_err.Error())
+					result = ImportResult{Path: importRow.Path, Status: importFailed, Error: dbError}
+				}
+				report.add(result)
+			})
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(parseErr, &maxBytesErr):
+				report.add(ImportResult{Status: importFailed, Error: requestTooLargeMessage})
+			case parseErr != nil:
+				report.add(ImportResult{Status: importFailed, Error: badRequest})
+			}
+			if importMode == importAtomicMode && report.Failed > 0 {
+				return errImportFailed
+			}
+			if dryRun {
+				return errDryRun
+			}
+			return nil
+		}
+		var db_err error
+		if isTransaction {
+			db_err = store.RunInTransaction(r.Context(), runImport)
+		} else {
+			db_err = runImport(store)
+		}
+		responseStatus := http.StatusOK
+		switch {
+		case errors.Is(db_err, errImportFailed):
+			report.rollback()
+			responseStatus = http.StatusUnprocessableEntity
+		case errors.Is(db_err, errDryRun):
+			report.discard()
+		case db_err != nil:
+			log.Println("importRedirects -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		auditSummary := report
+		auditSummary.Results = nil
+		auditState(r, nil, auditSummary)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(responseStatus)
+		w.Write(toJson(report))
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+const clientIpContextKey contextKey = "clientIp"
+
+var trustedProxies = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+
+func parseTrustedProxies(proxies string) []netip.Prefix {
+	trustedPrefixes := []netip.Prefix{}
+	for _, proxy := range strings.Split(proxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if len(proxy) == 0 {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			proxyAddr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				log.Println("Invalid Trusted Proxy:", proxy)
+				continue
+			}
+			trustedPrefixes = append(trustedPrefixes, netip.PrefixFrom(proxyAddr.Unmap(), proxyAddr.Unmap().BitLen()))
+			continue
+		}
+		proxyPrefix, prefixErr := netip.ParsePrefix(proxy)
+		if prefixErr != nil {
+			log.Println("Invalid Trusted Proxy:", proxy)
+			continue
+		}
+		trustedPrefixes = append(trustedPrefixes, proxyPrefix.Masked())
+	}
+	return trustedPrefixes
+}
+
+func isTrustedProxy(addr netip.Addr) bool {
+	for _, trustedPrefix := range trustedProxies {
+		if trustedPrefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseForwardedAddr(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if addrPort, addrPortErr := netip.ParseAddrPort(value); addrPortErr == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, addrErr := netip.ParseAddr(strings.Trim(value, "[]"))
+	return addr.Unmap(), addrErr == nil
+}
+
+func forwardedForChain(r *http.Request) []string {
+	forwardedChain := []string{}
+	for _, forwardedFor := range r.Header.Values("X-Forwarded-For") {
+		forwardedChain = append(forwardedChain, strings.Split(forwardedFor, ",")...)
+	}
+	if len(forwardedChain) > 0 {
+		return forwardedChain
+	}
+	for _, forwarded := range r.Header.Values("Forwarded") {
+		for _, forwardedElement := range strings.Split(forwarded, ",") {
+			for _, forwardedPair := range strings.Split(forwardedElement, ";") {
+				pairKey, pairValue, found := strings.Cut(strings.TrimSpace(forwardedPair), "=")
+				if found && strings.EqualFold(pairKey, "for") {
+					forwardedChain = append(forwardedChain, pairValue)
+				}
+			}
+		}
+	}
+	if len(forwardedChain) > 0 {
+		return forwardedChain
+	}
+	if realIp := r.Header.Get("X-Real-IP"); len(realIp) > 0 {
+		return []string{realIp}
+	}
+	return forwardedChain
+}
+
+func resolveClientIp(r *http.Request) string {
+	remoteHost, _, splitErr := net.SplitHostPort(r.RemoteAddr)
+	if splitErr != nil {
+		remoteHost = r.RemoteAddr
+	}
+	remoteAddr, addrErr := netip.ParseAddr(remoteHost)
+	if addrErr != nil || !isTrustedProxy(remoteAddr) {
+		return remoteHost
+	}
+	clientAddr := remoteAddr.Unmap()
+	forwardedChain := forwardedForChain(r)
+	for i := len(forwardedChain) - 1; i >= 0; i-- {
+		forwardedAddr, isAddrValid := parseForwardedAddr(forwardedChain[i])
+		if !isAddrValid {
+			break
+		}
+		clientAddr = forwardedAddr
+		if !isTrustedProxy(forwardedAddr) {
+			break
+		}
+	}
+	return clientAddr.String()
+}
+
+func clientIp(r *http.Request) string {
+	if resolvedIp, isResolved := r.Context().Value(clientIpContextKey).(string); isResolved {
+		return resolvedIp
+	}
+	return resolveClientIp(r)
+}
+
+func keyByClientIp(r *http.Request) (string, error) {
+	return clientIp(r), nil
+}
+
+func withClientIp(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIpContextKey, resolveClientIp(r))))
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+const dryRunCreate = "create"
+const dryRunUpdate = "update"
+const dryRunUnchanged = "unchanged"
+
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type DryRunResult struct {
+	DryRun   bool                   `json:"dryRun"`
+	Outcome  string                 `json:"outcome"`
+	Redirect Redirect               `json:"redirect"`
+	Changes  map[string]FieldChange `json:"changes,omitempty"`
+}
+
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	return dryRun
+}
+
+func redirectChanges(before Redirect, after Redirect) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	setChange := func(field string, isChanged bool, beforeValue any, afterValue any) {
+		if isChanged {
+			changes[field] = FieldChange{Before: beforeValue, After: afterValue}
+		}
+	}
+	setChange("path", before.Path != after.Path, before.Path, after.Path)
+	setChange("url", before.Url != after.Url, before.Url, after.Url)
+	setChange("tags", !slices.Equal(before.Tags, after.Tags), before.Tags, after.Tags)
+	setChange("statusCode", before.StatusCode != after.StatusCode, before.StatusCode, after.StatusCode)
+	setChange("title", before.Title != after.Title, before.Title, after.Title)
+	setChange("inactive", before.Inactive != after.Inactive, before.Inactive, after.Inactive)
+	setChange("folder", before.Folder != after.Folder, before.Folder, after.Folder)
+	setChange("expiresAt", before.ExpiresAt != after.ExpiresAt, before.ExpiresAt, after.ExpiresAt)
+	return changes
+}
+
+func runRedirectMutation(r *http.Request, store Store, mutate func(RedirectStore) error) error {
+	db_err := store.RunInTransaction(r.Context(), func(redirectStore RedirectStore) error {
+		if mutateErr := mutate(redirectStore); mutateErr != nil || !isDryRun(r) {
+			return mutateErr
+		}
+		return errDryRun
+	})
+	if errors.Is(db_err, errDryRun) {
+		return nil
+	}
+	return db_err
+}
+
+func writeRedirectMutation(w http.ResponseWriter, r *http.Request, before Redirect, after Redirect, statusCode int) {
+	var auditBefore any
+	if before.Id > 0 {
+		auditBefore = before
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !isDryRun(r) {
+		auditState(r, auditBefore, after)
+		w.WriteHeader(statusCode)
+		w.Write(toJson(after))
+		return
+	}
+	result := DryRunResult{DryRun: true, Outcome: dryRunUnchanged, Redirect: after}
+	switch changes := redirectChanges(before, after); {
+	case before.Id == 0:
+		result.Outcome, result.Redirect.Id = dryRunCreate, 0
+	case len(changes) > 0:
+		result.Outcome, result.Changes = dryRunUpdate, changes
+	}
+	auditState(r, auditBefore, result)
+	w.WriteHeader(http.StatusOK)
+	w.Write(toJson(result))
+}
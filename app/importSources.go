@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const bitlyFormat = "bitly"
+const yourlsFormat = "yourls"
+const kuttFormat = "kutt"
+const apacheFormat = "apache"
+
+type ImportSource struct {
+	pathFields    []string
+	urlFields     []string
+	createdFields []string
+	clicksFields  []string
+	tagsFields    []string
+	titleFields   []string
+}
+
+var importSources = map[string]ImportSource{
+	bitlyFormat: {
+		pathFields:    []string{"link", "bitlink", "shortlink", "shorturl", "id"},
+		urlFields:     []string{"longurl", "destination", "originalurl", "url"},
+		createdFields: []string{"createdat", "created", "datecreated", "creationdate"},
+		clicksFields:  []string{"clicks", "totalclicks", "engagements"},
+		tagsFields:    []string{"tags"},
+		titleFields:   []string{"title"},
+	},
+	yourlsFormat: {
+		pathFields:    []string{"keyword", "shorturl"},
+		urlFields:     []string{"url", "longurl"},
+		createdFields: []string{"timestamp", "date"},
+		clicksFields:  []string{"clicks"},
+		titleFields:   []string{"title"},
+	},
+	kuttFormat: {
+		pathFields:    []string{"address", "link", "shorturl"},
+		urlFields:     []string{"target", "url"},
+		createdFields: []string{"createdat"},
+		clicksFields:  []string{"visitcount", "visits", "clicks"},
+		tagsFields:    []string{"tags"},
+		titleFields:   []string{"description", "title"},
+	},
+}
+
+var sourceJsonWrappers = []string{"links", "data", "urls", "items"}
+var sourceTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05-0700", "2006-01-02T15:04:05", "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05", "2006-01-02", "01/02/2006 15:04:05", "01/02/2006 15:04", "01/02/2006"}
+var apacheStatusKeywords = map[string]int{"permanent": 301, "temp": 302, "seeother": 303, "gone": 410}
+var apacheDynamicRegex = regexp.MustCompile(`\$|%\{`)
+var apacheIgnoredDirectives = []string{"rewriteengine", "rewritebase", "rewriteoptions", "options"}
+
+func normalizeFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func parseSourceTime(value string) time.Time {
+	if unixTime, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unixTime, 0)
+	}
+	for _, layout := range sourceTimeLayouts {
+		if parsedTime, err := time.Parse(layout, value); err == nil {
+			return parsedTime
+		}
+	}
+	return time.Time{}
+}
+
+func parseSourcePath(value string) string {
+	switch {
+	case len(value) == 0:
+		return value
+	case !strings.Contains(value, "/"):
+		return "/" + value
+	}
+	if !strings.Contains(value, "://") {
+		value = httpsProtocol + value
+	}
+	parsedUri, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+	return parsedUri.Path
+}
+
+func (importSource ImportSource) row(fields map[string]string) ImportRow {
+	field := func(names []string) string {
+		for _, name := range names {
+			if value := strings.TrimSpace(fields[name]); len(value) > 0 {
+				return value
+			}
+		}
+		return ""
+	}
+	importRow := ImportRow{UrlData: UrlData{Path: parseSourcePath(field(importSource.pathFields)), Url: field(importSource.urlFields), Title: field(importSource.titleFields)}}
+	if len(importRow.Path) == 0 || len(importRow.Url) == 0 {
+		importRow.Unsupported = "missing short link or destination"
+	}
+	if tags := field(importSource.tagsFields); len(tags) > 0 {
+		importRow.Tags = []string{tags}
+	}
+	importRow.CreatedAt = parseSourceTime(field(importSource.createdFields))
+	importRow.Clicks, _ = strconv.ParseInt(strings.ReplaceAll(field(importSource.clicksFields), ",", ""), 10, 64)
+	return importRow
+}
+
+func sourceJsonValue(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			values = append(values, sourceJsonValue(item))
+		}
+		return strings.Join(values, ",")
+	}
+	return ""
+}
+
+func sourceJsonRecords(document any) []map[string]any {
+	var records []map[string]any
+	switch document := document.(type) {
+	case []any:
+		for _, item := range document {
+			if record, isRecord := item.(map[string]any); isRecord {
+				records = append(records, record)
+			}
+		}
+	case map[string]any:
+		for _, wrapper := range sourceJsonWrappers {
+			if nested, found := document[wrapper]; found {
+				return sourceJsonRecords(nested)
+			}
+		}
+		keys := make([]string, 0, len(document))
+		for key := range document {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(x, y string) int {
+			if len(x) != len(y) {
+				return len(x) - len(y)
+			}
+			return strings.Compare(x, y)
+		})
+		for _, key := range keys {
+			if record, isRecord := document[key].(map[string]any); isRecord {
+				records = append(records, record)
+			}
+		}
+	}
+	return records
+}
+
+func readSourceJson(body io.Reader, importSource ImportSource, handleRow func(ImportRow)) error {
+	var document any
+	if err := json.NewDecoder(body).Decode(&document); err != nil {
+		return err
+	}
+	for _, record := range sourceJsonRecords(document) {
+		fields := make(map[string]string, len(record))
+		for key, value := range record {
+			fields[normalizeFieldName(key)] = sourceJsonValue(value)
+		}
+		handleRow(importSource.row(fields))
+	}
+	return nil
+}
+
+func readSourceCsv(body io.Reader, importSource ImportSource, handleRow func(ImportRow)) error {
+	csvReader := csv.NewReader(body)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+	for i, column := range header {
+		header[i] = normalizeFieldName(column)
+	}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fields := make(map[string]string, len(header))
+		for i, value := range record {
+			if i < len(header) {
+				fields[header[i]] = value
+			}
+		}
+		handleRow(importSource.row(fields))
+	}
+}
+
+func readSourceRows(body io.Reader, importSource ImportSource, handleRow func(ImportRow)) error {
+	bufferedBody := bufio.NewReader(body)
+	for {
+		r, _, err := bufferedBody.ReadRune()
+		if err != nil {
+			return err
+		}
+		if unicode.IsSpace(r) || r == '\ufeff' {
+			continue
+		}
+		bufferedBody.UnreadRune()
+		if r == '{' || r == '[' {
+			return readSourceJson(bufferedBody, importSource, handleRow)
+		}
+		return readSourceCsv(bufferedBody, importSource, handleRow)
+	}
+}
+
+func splitApacheArgs(line string) []string {
+	var args []string
+	var arg strings.Builder
+	isQuoted, hasArg := false, false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && isQuoted && i+1 < len(line) && line[i+1] == '"':
+			arg.WriteByte('"')
+			i++
+		case c == '"':
+			isQuoted, hasArg = !isQuoted, true
+		case (c == ' ' || c == '\t') && !isQuoted:
+			if hasArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				hasArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+func apacheLiteralPath(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, "^") || !strings.HasSuffix(pattern, "$") || strings.HasSuffix(pattern, "\\$") {
+		return "", false
+	}
+	pattern = strings.TrimPrefix(strings.TrimPrefix(pattern[1:len(pattern)-1], "/?"), "/")
+	var path strings.Builder
+	path.WriteByte('/')
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern) && !unicode.IsLetter(rune(pattern[i+1])) && !unicode.IsDigit(rune(pattern[i+1])):
+			path.WriteByte(pattern[i+1])
+			i++
+		case strings.IndexByte("\\[](){}*+?|^$", c) >= 0:
+			return "", false
+		default:
+			path.WriteByte(c)
+		}
+	}
+	return path.String(), true
+}
+
+func apacheRedirectStatus(args []string) (int, []string, string) {
+	if len(args) == 0 {
+		return 302, args, ""
+	}
+	if statusCode, isKeyword := apacheStatusKeywords[strings.ToLower(args[0])]; isKeyword {
+		return statusCode, args[1:], ""
+	}
+	if statusCode, err := strconv.Atoi(args[0]); err == nil {
+		return statusCode, args[1:], ""
+	}
+	if len(args) > 2 {
+		return 0, args, "unknown status " + args[0]
+	}
+	return 302, args, ""
+}
+
+func apacheRewriteStatus(flags string) (int, bool) {
+	for _, flag := range strings.Split(strings.Trim(flags, "[]"), ",") {
+		name, value, hasValue := strings.Cut(strings.ToLower(strings.TrimSpace(flag)), "=")
+		if name != "r" && name != "redirect" {
+			continue
+		}
+		if !hasValue {
+			return 302, true
+		}
+		if statusCode, isKeyword := apacheStatusKeywords[value]; isKeyword {
+			return statusCode, true
+		}
+		statusCode, err := strconv.Atoi(value)
+		return statusCode, err == nil
+	}
+	return 0, false
+}
+
+func parseApacheRule(args []string) (UrlData, string) {
+	name, directive := args[0], strings.ToLower(args[0])
+	args = args[1:]
+	var requestData UrlData
+	switch directive {
+	case "redirect", "redirectmatch":
+		statusCode, remaining, reason := apacheRedirectStatus(args)
+		if len(reason) > 0 {
+			return requestData, reason
+		}
+		requestData.StatusCode, args = statusCode, remaining
+		if !slices.Contains(redirectStatusCodes, statusCode) {
+			return requestData, "status " + strconv.Itoa(statusCode)
+		}
+	case "redirectpermanent":
+		requestData.StatusCode = 301
+	case "redirecttemp":
+		requestData.StatusCode = 302
+	case "rewriterule":
+		if len(args) < 3 {
+			return requestData, "not an external redirect"
+		}
+		statusCode, isRedirect := apacheRewriteStatus(args[2])
+		if !isRedirect {
+			return requestData, "not an external redirect"
+		}
+		requestData.StatusCode, args = statusCode, args[:2]
+	default:
+		return requestData, "unknown directive " + name
+	}
+	if len(args) != 2 {
+		return requestData, "expected a path and a target"
+	}
+	requestData.Path, requestData.Url = args[0], args[1]
+	if directive == "redirectmatch" || directive == "rewriterule" {
+		literalPath, isLiteral := apacheLiteralPath(args[0])
+		if !isLiteral {
+			return requestData, "pattern is not a literal path"
+		}
+		requestData.Path = literalPath
+	}
+	switch {
+	case !strings.HasPrefix(requestData.Path, "/"):
+		return requestData, "path must start with /"
+	case apacheDynamicRegex.MatchString(requestData.Url) || (directive == "rewriterule" && strings.Contains(requestData.Url, "%")):
+		return requestData, "dynamic target"
+	case !strings.Contains(requestData.Url, "://"):
+		return requestData, "relative target"
+	case !slices.Contains(redirectStatusCodes, requestData.StatusCode):
+		return requestData, "status " + strconv.Itoa(requestData.StatusCode)
+	}
+	return requestData, ""
+}
+
+func readApacheRules(body io.Reader, handleRow func(ImportRow)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var conditions []string
+	var pendingLine string
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(pendingLine + scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			pendingLine = strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		pendingLine = ""
+		args := splitApacheArgs(strings.TrimPrefix(line, "\ufeff"))
+		if len(args) == 0 || strings.HasPrefix(args[0], "#") || strings.HasPrefix(args[0], "<") {
+			continue
+		}
+		directive := strings.ToLower(args[0])
+		switch {
+		case slices.Contains(apacheIgnoredDirectives, directive):
+			continue
+		case directive == "rewritecond":
+			conditions = append(conditions, line)
+			continue
+		}
+		importRow := ImportRow{Source: fmt.Sprintf("line %d: %s", lineNumber, line)}
+		importRow.UrlData, importRow.Unsupported = parseApacheRule(args)
+		if directive == "rewriterule" && len(conditions) > 0 {
+			importRow.Unsupported = fmt.Sprintf("conditional rule (%d RewriteCond)", len(conditions))
+		}
+		if directive == "rewriterule" {
+			conditions = nil
+		}
+		handleRow(importRow)
+	}
+	return scanner.Err()
+}
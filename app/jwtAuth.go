@@ -0,0 +1,382 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiKeyAuthMode = "apikey"
+const jwtAuthMode = "jwt"
+const anyAuthMode = "any"
+
+const jwksRefreshInterval = 10 * time.Minute
+const jwksMinRefreshInterval = time.Minute
+const jwtClockLeeway = time.Minute
+
+var envAuthMode = strings.ToLower(strings.TrimSpace(os.Getenv("AUTH_MODE")))
+var envJwksSource = strings.TrimSpace(os.Getenv("AUTH_JWKS"))
+var envJwtIssuer = strings.TrimSpace(os.Getenv("AUTH_JWT_ISSUER"))
+var envJwtAudience = strings.TrimSpace(os.Getenv("AUTH_JWT_AUDIENCE"))
+var envJwtRoleClaims = os.Getenv("AUTH_JWT_ROLE_CLAIMS")
+var envJwtRoleScopes = os.Getenv("AUTH_JWT_ROLE_SCOPES")
+
+var jwtKeys = &JwksCache{source: envJwksSource}
+var jwtRoleScopes = parseJwtRoleScopes(envJwtRoleScopes)
+
+type JwksCache struct {
+	source    string
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func getAuthMode() string {
+	switch envAuthMode {
+	case apiKeyAuthMode, jwtAuthMode:
+		return envAuthMode
+	default:
+		return anyAuthMode
+	}
+}
+
+func isJwtAuthEnabled() bool {
+	return len(envJwksSource) > 0 && getAuthMode() != apiKeyAuthMode
+}
+
+func isApiKeyAuthEnabled() bool {
+	return getAuthMode() != jwtAuthMode
+}
+
+func getJwtRoleClaims() []string {
+	roleClaims := []string{}
+	for _, roleClaim := range strings.Split(envJwtRoleClaims, ",") {
+		if roleClaim = strings.TrimSpace(roleClaim); len(roleClaim) > 0 {
+			roleClaims = append(roleClaims, roleClaim)
+		}
+	}
+	if len(roleClaims) == 0 {
+		return []string{"groups", "roles"}
+	}
+	return roleClaims
+}
+
+func parseJwtRoleScopes(roleScopes string) map[string][]string {
+	roleScopeMap := map[string][]string{}
+	for _, roleScope := range strings.Split(roleScopes, ",") {
+		role, scopes, found := strings.Cut(roleScope, "=")
+		role = strings.TrimSpace(role)
+		if !found || len(role) == 0 {
+			continue
+		}
+		for _, scope := range strings.Split(scopes, "|") {
+			scope = strings.TrimSpace(scope)
+			if slices.Contains(apiKeyScopes, scope) && !slices.Contains(roleScopeMap[role], scope) {
+				roleScopeMap[role] = append(roleScopeMap[role], scope)
+			}
+		}
+	}
+	return roleScopeMap
+}
+
+func initJwtAuth() {
+	if getAuthMode() == jwtAuthMode && len(envJwksSource) == 0 {
+		log.Fatalln("AUTH_MODE=jwt requires AUTH_JWKS")
+	}
+	if !isJwtAuthEnabled() {
+		return
+	}
+	if err := jwtKeys.refresh(); err != nil {
+		log.Printf("JWKS Load Error: %v (JWT authentication will fail until %s can be loaded)\n", err, envJwksSource)
+		return
+	}
+	log.Println("JWKS loaded from", envJwksSource)
+}
+
+func readJwks(source string) ([]byte, error) {
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		client := &http.Client{Timeout: 5 * time.Second}
+		res, err := client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("JWKS request failed with %s", res.Status)
+		}
+		return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	}
+	return os.ReadFile(source)
+}
+
+func (cache *JwksCache) refresh() error {
+	jwksBytes, err := readJwks(cache.source)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.fetchedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	var jwks struct {
+		Keys []Jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(jwksBytes, &jwks); err != nil {
+		return err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, keyErr := jwk.publicKey()
+		if keyErr != nil {
+			log.Printf("JWKS key %s skipped: %v\n", jwk.Kid, keyErr)
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+	cache.keys = keys
+	return nil
+}
+
+func (cache *JwksCache) claimRefresh(found bool) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	sinceFetch := time.Since(cache.fetchedAt)
+	if (found && sinceFetch < jwksRefreshInterval) || (!found && sinceFetch < jwksMinRefreshInterval) {
+		return false
+	}
+	cache.fetchedAt = time.Now()
+	return true
+}
+
+func (cache *JwksCache) key(kid string) (crypto.PublicKey, bool) {
+	cache.mu.RLock()
+	publicKey, found := cache.keys[kid]
+	cache.mu.RUnlock()
+	if !cache.claimRefresh(found) {
+		return publicKey, found
+	}
+	if err := cache.refresh(); err != nil {
+		log.Println("JWKS Refresh Error:", err)
+		return publicKey, found
+	}
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	publicKey, found = cache.keys[kid]
+	return publicKey, found
+}
+
+func decodeJwkInt(value string) (*big.Int, error) {
+	intBytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(intBytes) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(intBytes), nil
+}
+
+func (jwk Jwk) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, nErr := decodeJwkInt(jwk.N)
+		e, eErr := decodeJwkInt(jwk.E)
+		if nErr != nil || eErr != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, xErr := decodeJwkInt(jwk.X)
+		y, yErr := decodeJwkInt(jwk.Y)
+		if xErr != nil || yErr != nil || !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func jwtHashFunc(alg string) (crypto.Hash, bool) {
+	switch alg[len(alg)-3:] {
+	case "256":
+		return crypto.SHA256, true
+	case "384":
+		return crypto.SHA384, true
+	case "512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+func verifyJwtSignature(alg string, publicKey crypto.PublicKey, signingInput string, signature []byte) bool {
+	if len(alg) != 5 {
+		return false
+	}
+	hashFunc, isHashValid := jwtHashFunc(alg)
+	if !isHashValid {
+		return false
+	}
+	hasher := hashFunc.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(key, hashFunc, digest, signature) == nil
+		case "PS":
+			return rsa.VerifyPSS(key, hashFunc, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		keySize := (key.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(signature) != 2*keySize {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:keySize])
+		s := new(big.Int).SetBytes(signature[keySize:])
+		return ecdsa.Verify(key, digest, r, s)
+	}
+	return false
+}
+
+func parseJwt(token string) (map[string]any, error) {
+	tokenParts := strings.Split(token, ".")
+	if len(tokenParts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	headerBytes, headerErr := base64.RawURLEncoding.DecodeString(tokenParts[0])
+	claimsBytes, claimsErr := base64.RawURLEncoding.DecodeString(tokenParts[1])
+	signature, signatureErr := base64.RawURLEncoding.DecodeString(tokenParts[2])
+	if headerErr != nil || claimsErr != nil || signatureErr != nil {
+		return nil, errors.New("malformed token encoding")
+	}
+	var header JwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, err
+	}
+	publicKey, found := jwtKeys.key(header.Kid)
+	if !found {
+		return nil, fmt.Errorf("unknown key id %q", header.Kid)
+	}
+	if !verifyJwtSignature(header.Alg, publicKey, tokenParts[0]+"."+tokenParts[1], signature) {
+		return nil, errors.New("invalid signature")
+	}
+	claims := map[string]any{}
+	if err := json.Unmarshal(claimsBytes, &claims); err != nil {
+		return nil, err
+	}
+	return claims, validateJwtClaims(claims)
+}
+
+func jwtTimeClaim(claims map[string]any, name string) (time.Time, bool) {
+	value, isNumber := claims[name].(float64)
+	if !isNumber {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
+
+func jwtStringsClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		values := []string{}
+		for _, item := range value {
+			if itemValue, isString := item.(string); isString {
+				values = append(values, itemValue)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func validateJwtClaims(claims map[string]any) error {
+	now := time.Now()
+	expiresAt, hasExpiry := jwtTimeClaim(claims, "exp")
+	if !hasExpiry || now.After(expiresAt.Add(jwtClockLeeway)) {
+		return errors.New("token expired")
+	}
+	if notBefore, hasNotBefore := jwtTimeClaim(claims, "nbf"); hasNotBefore && now.Add(jwtClockLeeway).Before(notBefore) {
+		return errors.New("token not yet valid")
+	}
+	if issuer, _ := claims["iss"].(string); len(envJwtIssuer) > 0 && issuer != envJwtIssuer {
+		return errors.New("issuer mismatch")
+	}
+	if len(envJwtAudience) > 0 && !slices.Contains(jwtStringsClaim(claims, "aud"), envJwtAudience) {
+		return errors.New("audience mismatch")
+	}
+	return nil
+}
+
+func jwtClaimScopes(claims map[string]any) []string {
+	scopes := []string{}
+	for _, roleClaim := range getJwtRoleClaims() {
+		for _, role := range jwtStringsClaim(claims, roleClaim) {
+			for _, scope := range jwtRoleScopes[role] {
+				if !slices.Contains(scopes, scope) {
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+	}
+	return scopes
+}
+
+func authenticateJwt(token string) (ApiCaller, bool) {
+	claims, err := parseJwt(token)
+	if err != nil {
+		log.Println("authenticateJwt -> ", err.Error())
+		return ApiCaller{}, false
+	}
+	subject, _ := claims["sub"].(string)
+	scopes := jwtClaimScopes(claims)
+	if len(subject) == 0 || len(scopes) == 0 {
+		return ApiCaller{}, false
+	}
+	return ApiCaller{KeyId: jwtAuthMode + ":" + subject, Scopes: scopes}, true
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testJwtKeys struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+}
+
+func encodeJwkInt(value *big.Int, size int) string {
+	valueBytes := value.Bytes()
+	if len(valueBytes) < size {
+		valueBytes = append(make([]byte, size-len(valueBytes)), valueBytes...)
+	}
+	return base64.RawURLEncoding.EncodeToString(valueBytes)
+}
+
+func setupTestJwks(t *testing.T) testJwtKeys {
+	t.Helper()
+	rsaKey, rsaErr := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, ecErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if rsaErr != nil || ecErr != nil {
+		t.Fatalf("key generation failed: %v %v", rsaErr, ecErr)
+	}
+	jwks := map[string][]Jwk{"keys": {
+		{Kty: "RSA", Kid: "rsa", Use: "sig", N: encodeJwkInt(rsaKey.N, 0), E: encodeJwkInt(big.NewInt(int64(rsaKey.E)), 0)},
+		{Kty: "EC", Kid: "ec", Crv: "P-256", X: encodeJwkInt(ecKey.X, 32), Y: encodeJwkInt(ecKey.Y, 32)},
+	}}
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if writeErr := os.WriteFile(jwksPath, toJson(jwks), 0o600); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	previousKeys, previousIssuer, previousAudience, previousRoleScopes := jwtKeys, envJwtIssuer, envJwtAudience, jwtRoleScopes
+	t.Cleanup(func() {
+		jwtKeys, envJwtIssuer, envJwtAudience, jwtRoleScopes = previousKeys, previousIssuer, previousAudience, previousRoleScopes
+	})
+	jwtKeys = &JwksCache{source: jwksPath}
+	if refreshErr := jwtKeys.refresh(); refreshErr != nil {
+		t.Fatal(refreshErr)
+	}
+	envJwtIssuer, envJwtAudience = "", ""
+	return testJwtKeys{rsaKey: rsaKey, ecKey: ecKey}
+}
+
+func (keys testJwtKeys) sign(t *testing.T, alg string, kid string, claims map[string]any) string {
+	t.Helper()
+	signingInput := base64.RawURLEncoding.EncodeToString(toJson(JwtHeader{Alg: alg, Kid: kid})) + "." + base64.RawURLEncoding.EncodeToString(toJson(claims))
+	digest := sha256.Sum256([]byte(signingInput))
+	var signature []byte
+	var signErr error
+	switch alg {
+	case "RS256":
+		signature, signErr = rsa.SignPKCS1v15(rand.Reader, keys.rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		signature, signErr = rsa.SignPSS(rand.Reader, keys.rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		r, s, ecErr := ecdsa.Sign(rand.Reader, keys.ecKey, digest[:])
+		signature, signErr = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...), ecErr
+	}
+	if signErr != nil {
+		t.Fatal(signErr)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix(), "groups": []string{"editors"}}
+}
+
+func TestJwtSignatureAlgorithms(t *testing.T) {
+	keys := setupTestJwks(t)
+	for _, test := range []struct{ alg, kid string }{{"RS256", "rsa"}, {"PS256", "rsa"}, {"ES256", "ec"}} {
+		token := keys.sign(t, test.alg, test.kid, validClaims())
+		if _, err := parseJwt(token); err != nil {
+			t.Errorf("%s: valid token rejected: %v", test.alg, err)
+		}
+		tampered := token[:len(token)-4] + "AAAA"
+		if _, err := parseJwt(tampered); err == nil {
+			t.Errorf("%s: tampered token accepted", test.alg)
+		}
+	}
+	if _, err := parseJwt(keys.sign(t, "ES256", "rsa", validClaims())); err == nil {
+		t.Error("ES256 token verified with an RSA key")
+	}
+}
+
+func TestJwtKeyId(t *testing.T) {
+	keys := setupTestJwks(t)
+	if _, err := parseJwt(keys.sign(t, "RS256", "missing", validClaims())); err == nil {
+		t.Error("token with unknown kid accepted")
+	}
+	if _, err := parseJwt(keys.sign(t, "RS256", "ec", validClaims())); err == nil {
+		t.Error("token signed with the wrong kid accepted")
+	}
+}
+
+func TestJwtTimeClaims(t *testing.T) {
+	keys := setupTestJwks(t)
+	now := time.Now()
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		isValid bool
+	}{
+		{"missing exp", map[string]any{"sub": "alice"}, false},
+		{"expired within leeway", map[string]any{"exp": now.Add(-jwtClockLeeway / 2).Unix()}, true},
+		{"expired beyond leeway", map[string]any{"exp": now.Add(-2 * jwtClockLeeway).Unix()}, false},
+		{"nbf within leeway", map[string]any{"exp": now.Add(time.Hour).Unix(), "nbf": now.Add(jwtClockLeeway / 2).Unix()}, true},
+		{"nbf beyond leeway", map[string]any{"exp": now.Add(time.Hour).Unix(), "nbf": now.Add(2 * jwtClockLeeway).Unix()}, false},
+	}
+	for _, test := range tests {
+		_, err := parseJwt(keys.sign(t, "RS256", "rsa", test.claims))
+		if (err == nil) != test.isValid {
+			t.Errorf("%s: got error %v, want valid %t", test.name, err, test.isValid)
+		}
+	}
+}
+
+func TestJwtIssuerAudience(t *testing.T) {
+	keys := setupTestJwks(t)
+	envJwtIssuer, envJwtAudience = "https://sso.example.com", "url-redirect"
+	claims := validClaims()
+	claims["iss"], claims["aud"] = "https://sso.example.com", []string{"other", "url-redirect"}
+	if _, err := parseJwt(keys.sign(t, "RS256", "rsa", claims)); err != nil {
+		t.Errorf("matching iss/aud rejected: %v", err)
+	}
+	claims["iss"] = "https://evil.example.com"
+	if _, err := parseJwt(keys.sign(t, "RS256", "rsa", claims)); err == nil {
+		t.Error("issuer mismatch accepted")
+	}
+	claims["iss"], claims["aud"] = "https://sso.example.com", "other"
+	if _, err := parseJwt(keys.sign(t, "RS256", "rsa", claims)); err == nil {
+		t.Error("audience mismatch accepted")
+	}
+}
+
+func TestJwtRoleScopes(t *testing.T) {
+	keys := setupTestJwks(t)
+	jwtRoleScopes = parseJwtRoleScopes("editors=read|write, admins=admin, viewers=read|bogus")
+	if scopes := jwtRoleScopes["viewers"]; !slices.Equal(scopes, []string{readScope}) {
+		t.Errorf("unknown scope not dropped: %v", scopes)
+	}
+	claims := validClaims()
+	claims["roles"] = "viewers admins"
+	caller, isAuthenticated := authenticateJwt(keys.sign(t, "ES256", "ec", claims))
+	if !isAuthenticated || caller.KeyId != "jwt:alice" {
+		t.Fatalf("authenticateJwt = %+v, %t", caller, isAuthenticated)
+	}
+	if !slices.Equal(caller.Scopes, []string{readScope, writeScope, adminScope}) {
+		t.Errorf("scopes = %v", caller.Scopes)
+	}
+	claims["groups"], claims["roles"] = []string{"nobody"}, nil
+	if _, isAuthenticated := authenticateJwt(keys.sign(t, "ES256", "ec", claims)); isAuthenticated {
+		t.Error("token without a mapped role authenticated")
+	}
+	var decoded map[string]any
+	json.Unmarshal(toJson(claims), &decoded)
+	if len(jwtClaimScopes(decoded)) != 0 {
+		t.Error("unmapped roles granted scopes")
+	}
+}
+
+func TestJwksRefreshIsShared(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer server.Close()
+	cache := &JwksCache{source: server.URL}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.key("unknown-" + string(rune('a'+i)))
+		}()
+	}
+	wg.Wait()
+	if fetchCount := fetches.Load(); fetchCount != 1 {
+		t.Errorf("JWKS fetched %d times for concurrent unknown kids", fetchCount)
+	}
+}
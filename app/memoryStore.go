@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryRedirect struct {
+	Redirect
+	createdAt time.Time
+	createdBy string
+}
+
+type memoryAnalyticsLog struct {
+	AnalyticsLog
+	timestamp time.Time
+}
+
+type memoryApiKey struct {
+	ApiKey
+	secretHash string
+	expiresAt  *time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.RWMutex
+	writeMu   sync.Mutex
+	nextId    int
+	redirects []memoryRedirect
+	analytics []memoryAnalyticsLog
+	apiKeys   []memoryApiKey
+	auditLogs []AuditLog
+}
+
+func newMemoryStore() *MemoryStore {
+	return &MemoryStore{nextId: 1}
+}
+
+func (store *MemoryStore) Close() {}
+
+func (store *MemoryStore) RunInTransaction(ctx context.Context, run func(RedirectStore) error) error {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	store.mu.RLock()
+	txStore := &MemoryStore{nextId: store.nextId, redirects: slices.Clone(store.redirects)}
+	store.mu.RUnlock()
+	if err := run(txStore); err != nil {
+		return err
+	}
+	store.mu.Lock()
+	store.nextId, store.redirects = txStore.nextId, txStore.redirects
+	store.mu.Unlock()
+	return nil
+}
+
+func (store *MemoryStore) findRedirect(match func(Redirect) bool) (Redirect, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	for _, redirect := range store.redirects {
+		if match(redirect.Redirect) {
+			return redirect.Redirect, nil
+		}
+	}
+	return Redirect{}, errNotFound
+}
+
+func (store *MemoryStore) GetRedirectUsingPath(ctx context.Context, path string) (Redirect, error) {
+	return store.findRedirect(func(redirect Redirect) bool { return redirect.Path == path })
+}
+
+func (store *MemoryStore) GetRedirectUsingId(ctx context.Context, id int) (Redirect, error) {
+	return store.findRedirect(func(redirect Redirect) bool { return redirect.Id == id })
+}
+
+func (store *MemoryStore) LockRedirect(ctx context.Context, id int) (Redirect, error) {
+	return store.GetRedirectUsingId(ctx, id)
+}
+
+func (store *MemoryStore) GetRedirectUsingCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error) {
+	activeRedirect, activeErr := store.findRedirect(func(redirect Redirect) bool { return redirect.CanonicalUrl == canonicalUrl && !redirect.Inactive })
+	if activeErr == nil {
+		return activeRedirect, nil
+	}
+	return store.findRedirect(func(redirect Redirect) bool { return redirect.CanonicalUrl == canonicalUrl })
+}
+
+func (store *MemoryStore) LockCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error) {
+	return store.GetRedirectUsingCanonicalUrl(ctx, canonicalUrl)
+}
+
+func (store *MemoryStore) findRedirectIndex(id int) (int, bool) {
+	return slices.BinarySearchFunc(store.redirects, id, func(redirect memoryRedirect, id int) int {
+		return redirect.Id - id
+	})
+}
+
+func (store *MemoryStore) NextRedirectId(ctx context.Context) (int, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.nextId, nil
+}
+
+func (store *MemoryStore) InsertRedirect(ctx context.Context, redirect Redirect, createdBy string) (Redirect, error) {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, existing := range store.redirects {
+		if existing.Path == redirect.Path {
+			return Redirect{}, errConflict
+		}
+	}
+	if redirect.Id <= 0 {
+		redirect.Id = store.nextId
+	}
+	index, found := store.findRedirectIndex(redirect.Id)
+	if found {
+		return Redirect{}, errConflict
+	}
+	now := time.Now()
+	createdAt, parseErr := time.Parse(timestampLayout, redirect.CreatedAt)
+	if parseErr != nil {
+		createdAt = now
+	}
+	store.nextId = max(store.nextId, redirect.Id+1)
+	redirect.StatusCode = redirectStatus(redirect.StatusCode, 0)
+	redirect.LastUpdated = formatTimestamp(now)
+	redirect.CreatedAt = formatTimestamp(createdAt)
+	redirect.Inactive = false
+	store.redirects = slices.Insert(store.redirects, index, memoryRedirect{Redirect: redirect, createdAt: createdAt, createdBy: createdBy})
+	return redirect, nil
+}
+
+func (store *MemoryStore) UpdateRedirect(ctx context.Context, redirect Redirect) (Redirect, error) {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	index, found := store.findRedirectIndex(redirect.Id)
+	if !found {
+		return Redirect{}, errNotFound
+	}
+	for _, existing := range store.redirects {
+		if existing.Path == redirect.Path && existing.Id != redirect.Id {
+			return Redirect{}, errConflict
+		}
+	}
+	redirect.LastUpdated = formatTimestamp(time.Now())
+	redirect.StatusCode = redirectStatus(redirect.StatusCode, store.redirects[index].StatusCode)
+	redirect.CreatedAt = store.redirects[index].CreatedAt
+	redirect.ImportedClicks = store.redirects[index].ImportedClicks
+	store.redirects[index].Redirect = redirect
+	return redirect, nil
+}
+
+func matchesRedirectFilter(redirect Redirect, filter RedirectFilter) bool {
+	host, _, _ := strings.Cut(redirect.CanonicalUrl, "/")
+	switch {
+	case filter.Inactive != nil && redirect.Inactive != *filter.Inactive:
+		return false
+	case len(filter.Tag) > 0 && !slices.Contains(redirect.Tags, filter.Tag):
+		return false
+	case redirect.LastUpdated < formatTimestamp(filter.UpdatedSince):
+		return false
+	case len(filter.Search) > 0 && !strings.Contains(strings.ToLower(redirect.Path), strings.ToLower(filter.Search)):
+		return false
+	case len(filter.Domain) > 0 && host != filter.Domain && !strings.HasSuffix(host, "."+filter.Domain):
+		return false
+	}
+	return true
+}
+
+func compareRedirects(x Redirect, y Redirect, sort string) int {
+	if sortOrder := strings.Compare(x.sortValue(sort), y.sortValue(sort)); sortOrder != 0 {
+		return sortOrder
+	}
+	return x.Id - y.Id
+}
+
+func (store *MemoryStore) ListRedirects(ctx context.Context, query RedirectQuery) ([]Redirect, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	cursor := Redirect{Id: query.AfterId, Path: query.AfterValue, LastUpdated: query.AfterValue, CreatedAt: query.AfterValue}
+	var responseData []Redirect
+	for _, redirect := range store.redirects {
+		if !matchesRedirectFilter(redirect.Redirect, query.RedirectFilter) {
+			continue
+		}
+		sortOrder := compareRedirects(redirect.Redirect, cursor, query.Sort)
+		if query.AfterId > 0 && ((!query.Descending && sortOrder <= 0) || (query.Descending && sortOrder >= 0)) {
+			continue
+		}
+		responseData = append(responseData, redirect.Redirect)
+	}
+	slices.SortFunc(responseData, func(x, y Redirect) int {
+		if query.Descending {
+			return compareRedirects(y, x, query.Sort)
+		}
+		return compareRedirects(x, y, query.Sort)
+	})
+	if len(responseData) > query.Limit {
+		responseData = responseData[:query.Limit]
+	}
+	return responseData, nil
+}
+
+func (store *MemoryStore) CountRedirects(ctx context.Context, filter RedirectFilter) (int, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	redirectCount := 0
+	for _, redirect := range store.redirects {
+		if matchesRedirectFilter(redirect.Redirect, filter) {
+			redirectCount++
+		}
+	}
+	return redirectCount, nil
+}
+
+func (store *MemoryStore) SearchRedirects(ctx context.Context, query SearchQuery) ([]SearchResult, int, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	redirects := make([]Redirect, 0, len(store.redirects))
+	for _, redirect := range store.redirects {
+		redirects = append(redirects, redirect.Redirect)
+	}
+	results, totalCount := rankRedirects(redirects, query)
+	return results, totalCount, nil
+}
+
+func (store *MemoryStore) CountRedirectsCreatedSince(ctx context.Context, createdBy string, since time.Time) (int, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	createdCount := 0
+	for _, redirect := range store.redirects {
+		if redirect.createdBy == createdBy && !redirect.createdAt.Before(since) {
+			createdCount++
+		}
+	}
+	return createdCount, nil
+}
+
+func (store *MemoryStore) BackfillCanonicalUrls(ctx context.Context, canonicalize func(string) string) (int, error) {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	backfilled := 0
+	for i, redirect := range store.redirects {
+		if len(redirect.CanonicalUrl) == 0 {
+			store.redirects[i].CanonicalUrl = canonicalize(redirect.Url)
+			backfilled++
+		}
+	}
+	return backfilled, nil
+}
+
+func (store *MemoryStore) InsertAnalyticsLog(ctx context.Context, logEntry AnalyticsLog) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.analytics = append(store.analytics, memoryAnalyticsLog{AnalyticsLog: logEntry, timestamp: time.Now()})
+	return nil
+}
+
+func (store *MemoryStore) GetStats(ctx context.Context, start time.Time, end time.Time) (LogStatsData, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	pathCounts, statusCounts, statusTimes := map[string]int64{}, map[string]int64{}, map[string]int64{}
+	var paths, statuses []string
+	for _, logEntry := range store.analytics {
+		if logEntry.timestamp.Before(start) || logEntry.timestamp.After(end) {
+			continue
+		}
+		status := strconv.Itoa(logEntry.Status)
+		if _, found := pathCounts[logEntry.Path]; !found {
+			paths = append(paths, logEntry.Path)
+		}
+		if _, found := statusCounts[status]; !found {
+			statuses = append(statuses, status)
+		}
+		pathCounts[logEntry.Path]++
+		statusCounts[status]++
+		statusTimes[status] += logEntry.ProcessingTime
+	}
+	statsData := LogStatsData{}
+	for _, path := range paths {
+		statsData.add("path", path, pathCounts[path])
+	}
+	for _, status := range statuses {
+		statsData.add("status", status, statusCounts[status])
+	}
+	for _, status := range statuses {
+		statsData.add("time", status, statusTimes[status]/statusCounts[status])
+	}
+	return statsData, nil
+}
+
+func (store *MemoryStore) findApiKey(keyId string) int {
+	return slices.IndexFunc(store.apiKeys, func(apiKey memoryApiKey) bool { return apiKey.KeyId == keyId })
+}
+
+func (store *MemoryStore) GetApiKeyCaller(ctx context.Context, keyId string) (ApiCaller, string, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	index := store.findApiKey(keyId)
+	if index < 0 {
+		return ApiCaller{}, "", errNotFound
+	}
+	apiKey := store.apiKeys[index]
+	if apiKey.Revoked || (apiKey.expiresAt != nil && !apiKey.expiresAt.After(time.Now())) {
+		return ApiCaller{}, "", errNotFound
+	}
+	return ApiCaller{KeyId: keyId, Scopes: apiKey.Scopes, RateLimit: apiKey.RateLimit, DailyQuota: apiKey.DailyQuota, MonthlyQuota: apiKey.MonthlyQuota}, apiKey.secretHash, nil
+}
+
+func (store *MemoryStore) TouchApiKey(ctx context.Context, keyId string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if index := store.findApiKey(keyId); index >= 0 {
+		store.apiKeys[index].LastUsedAt = formatTimestamp(time.Now())
+	}
+	return nil
+}
+
+func (store *MemoryStore) InsertApiKey(ctx context.Context, keyId string, secretHash string, keyData ApiKeyData) (ApiKey, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	apiKey := memoryApiKey{
+		ApiKey: ApiKey{
+			Id:           len(store.apiKeys) + 1,
+			KeyId:        keyId,
+			Name:         keyData.Name,
+			Scopes:       keyData.Scopes,
+			CreatedAt:    formatTimestamp(time.Now()),
+			RateLimit:    keyData.RateLimit,
+			DailyQuota:   keyData.DailyQuota,
+			MonthlyQuota: keyData.MonthlyQuota,
+		},
+		secretHash: secretHash,
+		expiresAt:  timeOrNil(keyData.ExpiresAt),
+	}
+	if apiKey.expiresAt != nil {
+		apiKey.ExpiresAt = formatTimestamp(*apiKey.expiresAt)
+	}
+	store.apiKeys = append(store.apiKeys, apiKey)
+	return apiKey.ApiKey, nil
+}
+
+func (store *MemoryStore) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	responseData := []ApiKey{}
+	for _, apiKey := range store.apiKeys {
+		responseData = append(responseData, apiKey.ApiKey)
+	}
+	return responseData, nil
+}
+
+func (store *MemoryStore) RevokeApiKey(ctx context.Context, keyId string) (ApiKey, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	index := store.findApiKey(keyId)
+	if index < 0 {
+		return ApiKey{}, errNotFound
+	}
+	store.apiKeys[index].Revoked = true
+	return store.apiKeys[index].ApiKey, nil
+}
+
+func (store *MemoryStore) InsertAuditLog(ctx context.Context, auditLog AuditLog) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	auditLog.Id = int64(len(store.auditLogs) + 1)
+	auditLog.Timestamp = formatTimestamp(time.Now())
+	store.auditLogs = append(store.auditLogs, auditLog)
+	return nil
+}
+
+func (store *MemoryStore) ListAuditLogs(ctx context.Context, auditQuery AuditQuery, limit int, offset int) ([]AuditLog, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	start, end := formatTimestamp(time.Unix(auditQuery.Start, 0)), formatTimestamp(time.Unix(auditQuery.End, 0))
+	responseData := []AuditLog{}
+	for i := len(store.auditLogs) - 1; i >= 0 && len(responseData) < limit; i-- {
+		auditLog := store.auditLogs[i]
+		if auditLog.Timestamp < start || auditLog.Timestamp > end {
+			continue
+		}
+		if (len(auditQuery.Path) > 0 && auditLog.RedirectPath != auditQuery.Path) || (len(auditQuery.Actor) > 0 && auditLog.Actor != auditQuery.Actor) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		responseData = append(responseData, auditLog)
+	}
+	return responseData, nil
+}
+
+func (store *MemoryStore) migrationDialect() string {
+	return ""
+}
+
+func (store *MemoryStore) lockMigrations(ctx context.Context) (func(), error) {
+	return func() {}, nil
+}
+
+func (store *MemoryStore) appliedMigrationVersions(ctx context.Context) ([]int, error) {
+	return []int{}, nil
+}
+
+func (store *MemoryStore) applyMigration(ctx context.Context, migration Migration, up bool) error {
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryTransactionRollbackKeepsConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	concurrentInsert := make(chan error)
+	txErr := store.RunInTransaction(ctx, func(redirectStore RedirectStore) error {
+		go func() {
+			_, insertErr := store.InsertRedirect(ctx, Redirect{Path: "outside", Url: "example.com"}, "")
+			concurrentInsert <- insertErr
+		}()
+		if _, insertErr := redirectStore.InsertRedirect(ctx, Redirect{Path: "inside", Url: "example.com"}, ""); insertErr != nil {
+			return insertErr
+		}
+		return errDryRun
+	})
+	if !errors.Is(txErr, errDryRun) {
+		t.Fatalf("RunInTransaction = %v", txErr)
+	}
+	if insertErr := <-concurrentInsert; insertErr != nil {
+		t.Fatal(insertErr)
+	}
+	if _, getErr := store.GetRedirectUsingPath(ctx, "inside"); !errors.Is(getErr, errNotFound) {
+		t.Errorf("rolled back insert is visible: %v", getErr)
+	}
+	if _, getErr := store.GetRedirectUsingPath(ctx, "outside"); getErr != nil {
+		t.Errorf("concurrent insert was lost: %v", getErr)
+	}
+}
+
+func TestMemoryTransactionCommit(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	txErr := store.RunInTransaction(ctx, func(redirectStore RedirectStore) error {
+		_, insertErr := redirectStore.InsertRedirect(ctx, Redirect{Path: "inside", Url: "example.com"}, "")
+		return insertErr
+	})
+	if txErr != nil {
+		t.Fatal(txErr)
+	}
+	if redirect, getErr := store.GetRedirectUsingPath(ctx, "inside"); getErr != nil || redirect.Id != 1 {
+		t.Errorf("committed insert = %+v, %v", redirect, getErr)
+	}
+	if nextId, _ := store.NextRedirectId(ctx); nextId != 2 {
+		t.Errorf("nextId = %d", nextId)
+	}
+}
+
+func TestMemoryLockRedirectHoldsOutsideWritesUntilCommit(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	store.InsertRedirect(ctx, Redirect{Path: "locked", Url: "example.com", Title: "before"}, "")
+	outsideUpdate := make(chan error, 1)
+	txErr := store.RunInTransaction(ctx, func(redirectStore RedirectStore) error {
+		redirect, lockErr := redirectStore.LockRedirect(ctx, 1)
+		if lockErr != nil {
+			return lockErr
+		}
+		go func() {
+			_, updateErr := store.UpdateRedirect(ctx, Redirect{Id: 1, Path: "locked", Url: "example.com", Title: "outside"})
+			outsideUpdate <- updateErr
+		}()
+		select {
+		case <-outsideUpdate:
+			t.Error("outside write completed while the redirect was locked")
+		case <-time.After(20 * time.Millisecond):
+		}
+		redirect.Title = "inside"
+		_, updateErr := redirectStore.UpdateRedirect(ctx, redirect)
+		return updateErr
+	})
+	if txErr != nil {
+		t.Fatal(txErr)
+	}
+	if updateErr := <-outsideUpdate; updateErr != nil {
+		t.Fatal(updateErr)
+	}
+	if redirect, _ := store.GetRedirectUsingId(ctx, 1); redirect.Title != "outside" {
+		t.Errorf("title = %q, want the outside write applied after commit", redirect.Title)
+	}
+}
+
+func TestMemoryBackfillCanonicalUrls(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	store.InsertRedirect(ctx, Redirect{Path: "legacy", Url: "Example.com/x"}, "")
+	store.InsertRedirect(ctx, Redirect{Path: "current", Url: "example.com/y", CanonicalUrl: "example.com/y"}, "")
+	backfilled, backfillErr := store.BackfillCanonicalUrls(ctx, strings.ToLower)
+	if backfillErr != nil || backfilled != 1 {
+		t.Fatalf("BackfillCanonicalUrls = %d, %v", backfilled, backfillErr)
+	}
+	if redirect, _ := store.GetRedirectUsingPath(ctx, "legacy"); redirect.CanonicalUrl != "example.com/x" {
+		t.Errorf("canonicalUrl = %q", redirect.CanonicalUrl)
+	}
+}
@@ -2,8 +2,9 @@ package main
 
 import (
 	"context"
-	"crypto/subtle"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"net/textproto"
 	"strconv"
@@ -11,13 +12,13 @@ import (
 	"time"
 
 	"github.com/go-chi/httprate"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 type AnalyticsLog struct {
 	Path              string
+	ClientIp          string
 	Status            int
 	ProcessingTime    int64
 	AdditionalHeaders string
@@ -85,6 +86,10 @@ func (w *AppResponseWriter) Status() int {
 	return w.statusCode
 }
 
+func (w *AppResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func initRuntimeMetrics() {
 	for k, v := range metricsList {
 		runtimeMetricsGuages[v] = prometheus.NewGauge(
@@ -108,10 +113,10 @@ func initMetrics() {
 	}
 }
 
-func startAnalyticsWorker(db *pgxpool.Pool) {
+func startAnalyticsWorker(store AnalyticsStore) {
 	go func() {
 		for logEntry := range analyticsChan {
-			_, err := db.Exec(context.Background(), `INSERT INTO UrlRedirects_Analytics (path, log_timestamp, status, processing_time, additional_headers) VALUES ($1,now(),$2,$3,$4)`, logEntry.Path, logEntry.Status, logEntry.ProcessingTime, logEntry.AdditionalHeaders)
+			err := store.InsertAnalyticsLog(context.Background(), logEntry)
 			if err != nil {
 				log.Println("Analytics Insert Error:", err)
 			}
@@ -119,35 +124,170 @@ func startAnalyticsWorker(db *pgxpool.Pool) {
 	}()
 }
 
-func httpRateLimit(next http.Handler) http.Handler {
-	return httprate.Limit(
-		getHttpRateLimit(),
+var rateLimitHeaders = httprate.ResponseHeaders{
+	Limit:      "RateLimit-Limit",
+	Remaining:  "RateLimit-Remaining",
+	RetryAfter: "Retry-After",
+}
+
+func setRateLimitReset(w http.ResponseWriter, windowLength time.Duration) {
+	resetIn := time.Until(time.Now().Truncate(windowLength).Add(windowLength))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(resetIn.Seconds()))))
+}
+
+func keyByApiCaller(r *http.Request) (string, error) {
+	if keyId := callerKeyId(r); len(keyId) > 0 {
+		return keyId, nil
+	}
+	return keyByClientIp(r)
+}
+
+func newRateLimit(requestLimit int, keyFunc httprate.KeyFunc, options ...httprate.Option) func(http.Handler) http.Handler {
+	limiter := httprate.NewRateLimiter(
+		requestLimit,
 		time.Second,
-		httprate.WithKeyFuncs(httprate.KeyByIP),
-	)(next)
+		append([]httprate.Option{httprate.WithKeyFuncs(keyFunc), httprate.WithResponseHeaders(rateLimitHeaders)}, options...)...,
+	)
+	return func(next http.Handler) http.Handler {
+		limitedNext := limiter.Handler(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			setRateLimitReset(w, time.Second)
+			if caller := apiCallerFromContext(r.Context()); caller != nil && caller.RateLimit > 0 {
+				r = r.WithContext(httprate.WithRequestLimit(r.Context(), caller.RateLimit))
+			}
+			limitedNext.ServeHTTP(w, r)
+		})
+	}
 }
 
-func verifyApiKey(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKeyHeader := r.Header.Get("x-url-redirect-token")
-		if subtle.ConstantTimeCompare([]byte(apiKeyHeader), []byte(apiKey)) != 1 {
-			http.Error(w, errorMessage, http.StatusUnauthorized)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+func httpRateLimit() func(http.Handler) http.Handler {
+	return newRateLimit(getHttpRateLimit(), keyByClientIp)
+}
+
+func apiRateLimit() func(http.Handler) http.Handler {
+	return newRateLimit(getApiRateLimit(), keyByApiCaller, httprate.WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, r, http.StatusTooManyRequests, rateLimitedCode, rateLimitedMessage)
+	}))
+}
+
+type QuotaError struct {
+	ResetAt time.Time
+}
+
+func (quotaErr *QuotaError) Error() string {
+	return quotaExceededMessage
+}
+
+func checkQuota(ctx context.Context, store RedirectStore) error {
+	caller := apiCallerFromContext(ctx)
+	if caller == nil || (caller.DailyQuota <= 0 && caller.MonthlyQuota <= 0) {
+		return nil
+	}
+	now := time.Now()
+	dailyCount, db_err := store.CountRedirectsCreatedSince(ctx, caller.KeyId, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	monthlyCount, monthly_db_err := store.CountRedirectsCreatedSince(ctx, caller.KeyId, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()))
+	switch {
+	case db_err != nil || monthly_db_err != nil:
+		return errors.Join(db_err, monthly_db_err)
+	case caller.MonthlyQuota > 0 && monthlyCount >= caller.MonthlyQuota:
+		return &QuotaError{ResetAt: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())}
+	case caller.DailyQuota > 0 && dailyCount >= caller.DailyQuota:
+		return &QuotaError{ResetAt: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())}
+	}
+	return nil
+}
+
+func insertRedirectWithinQuota(ctx context.Context, store RedirectStore, redirect Redirect, createdBy string) (Redirect, error) {
+	if quotaErr := checkQuota(ctx, store); quotaErr != nil {
+		return Redirect{}, quotaErr
+	}
+	return store.InsertRedirect(ctx, redirect, createdBy)
 }
 
-func logRequest(db *pgxpool.Pool) func(http.Handler) http.Handler {
+func writeQuotaError(w http.ResponseWriter, r *http.Request, quotaErr *QuotaError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quotaErr.ResetAt).Seconds()))))
+	writeApiError(w, r, http.StatusTooManyRequests, quotaExceededCode, quotaExceededMessage)
+}
+
+func enforceQuota(store Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var quotaErr *QuotaError
+			switch db_err := checkQuota(r.Context(), store); {
+			case errors.As(db_err, &quotaErr):
+				writeQuotaError(w, r, quotaErr)
+			case db_err != nil:
+				log.Println("enforceQuota -> ", db_err.Error())
+				writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func verifyApiKey(store Store) func(http.Handler) http.Handler {
+	failureLimit := getAuthFailureRateLimit()
+	authFailures := httprate.NewRateLimiter(failureLimit, time.Minute, httprate.WithResponseHeaders(httprate.ResponseHeaders{}))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientKey, _ := keyByClientIp(r)
+			if _, failureRate, _ := authFailures.Status(clientKey); failureRate >= float64(failureLimit) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Minute.Seconds())))
+				writeApiError(w, r, http.StatusTooManyRequests, rateLimitedCode, rateLimitedMessage)
+				return
+			}
+			caller, isAuthorized := authenticateRequest(r, store)
+			if !isAuthorized {
+				authFailures.OnLimit(w, r, clientKey)
+				recordAudit(store, r, &AuditEntry{}, http.StatusUnauthorized)
+				writeApiError(w, r, http.StatusUnauthorized, unauthorizedCode, unauthorizedMessage)
+				return
+			}
+			next.ServeHTTP(w, withApiCaller(r, caller))
+		})
+	}
+}
+
+func authenticateRequest(r *http.Request, store ApiKeyStore) (ApiCaller, bool) {
+	bearerToken, isBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if isBearer && isJwtAuthEnabled() {
+		return authenticateJwt(strings.TrimSpace(bearerToken))
+	}
+	if isApiKeyAuthEnabled() {
+		return authenticateApiKey(r.Context(), r.Header.Get("x-url-redirect-token"), store)
+	}
+	return ApiCaller{}, false
+}
+
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller := apiCallerFromContext(r.Context())
+			if caller == nil || !caller.hasScope(scope) {
+				writeApiError(w, r, http.StatusForbidden, insufficientScopeCode, insufficientScopeMessage)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func logRequest(store Store) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			startTime := time.Now()
 			reqPath := r.URL.Path
 			appResponse := &AppResponseWriter{ResponseWriter: w}
-			next.ServeHTTP(appResponse, r)
+			caller := &ApiCaller{}
+			next.ServeHTTP(appResponse, r.WithContext(context.WithValue(r.Context(), apiCallerContextKey, caller)))
 			processingTime := time.Since(startTime).Milliseconds()
 			if !skipLogging(r.URL.Path) {
-				log.Printf("%s %s %d %vms\n", r.Method, reqPath, appResponse.Status(), processingTime)
+				if len(caller.KeyId) > 0 {
+					log.Printf("%s %s %d %vms key=%s\n", r.Method, reqPath, appResponse.Status(), processingTime, caller.KeyId)
+				} else {
+					log.Printf("%s %s %d %vms\n", r.Method, reqPath, appResponse.Status(), processingTime)
+				}
 				additionalHeaders := make([]string, len(logAdditionalHeaders))
 				for i, logAdditionalHeader := range logAdditionalHeaders {
 					additionalHeaders[i] = r.Header.Get(textproto.CanonicalMIMEHeaderKey(logAdditionalHeader))
@@ -155,6 +295,7 @@ func logRequest(db *pgxpool.Pool) func(http.Handler) http.Handler {
 
 				analyticsChan <- AnalyticsLog{
 					Path:              reqPath,
+					ClientIp:          clientIp(r),
 					Status:            appResponse.Status(),
 					ProcessingTime:    processingTime,
 					AdditionalHeaders: strings.Join(additionalHeaders, "|"),
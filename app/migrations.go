@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+const migrationsDir = "migrations"
+
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+var redirectPathColumnRegex = regexp.MustCompile(`(?is)(?:CREATE TABLE IF NOT EXISTS UrlRedirects \([^;]*?\bpath|ALTER TABLE UrlRedirects ALTER COLUMN path TYPE) VARCHAR\((\d+)\)`)
+
+var errSchemaNewer = errors.New("database schema is newer than this binary")
+var errSchemaPending = errors.New("database schema has pending migrations")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationRunner interface {
+	migrationDialect() string
+	lockMigrations(ctx context.Context) (func(), error)
+	appliedMigrationVersions(ctx context.Context) ([]int, error)
+	applyMigration(ctx context.Context, migration Migration, up bool) error
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+	migrations := []Migration{}
+	if len(dialect) == 0 {
+		return migrations, nil
+	}
+	dialectDir := migrationsDir + "/" + dialect
+	migrationEntries, err := fs.ReadDir(migrationFiles, dialectDir)
+	if err != nil {
+		return nil, err
+	}
+	migrationsByVersion := map[int]*Migration{}
+	for _, migrationEntry := range migrationEntries {
+		fileMatches := migrationFileRegex.FindStringSubmatch(migrationEntry.Name())
+		if fileMatches == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", migrationEntry.Name())
+		}
+		version, _ := strconv.Atoi(fileMatches[1])
+		migrationSql, readErr := fs.ReadFile(migrationFiles, dialectDir+"/"+migrationEntry.Name())
+		if readErr != nil {
+			return nil, readErr
+		}
+		migration, found := migrationsByVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: fileMatches[2]}
+			migrationsByVersion[version] = migration
+		} else if migration.Name != fileMatches[2] {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		if fileMatches[3] == "up" {
+			migration.Up = string(migrationSql)
+		} else {
+			migration.Down = string(migrationSql)
+		}
+	}
+	for _, migration := range migrationsByVersion {
+		if len(migration.Up) == 0 {
+			return nil, fmt.Errorf("migration %d has no up script", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(x, y Migration) int {
+		return x.Version - y.Version
+	})
+	return migrations, nil
+}
+
+func migratedPathLength(dialect string) int {
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		log.Fatalln("Migration Error: ", err.Error())
+	}
+	pathLength := 0
+	for _, migration := range migrations {
+		for _, match := range redirectPathColumnRegex.FindAllStringSubmatch(migration.Up, -1) {
+			pathLength, _ = strconv.Atoi(match[1])
+		}
+	}
+	return pathLength
+}
+
+func latestMigrationVersion(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func withMigrationLock(runner MigrationRunner, migrationFunc func(context.Context, []Migration, []int) error) error {
+	migrations, err := loadMigrations(runner.migrationDialect())
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	unlock, err := runner.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	appliedVersions, err := runner.appliedMigrationVersions(ctx)
+	if err != nil {
+		return err
+	}
+	return migrationFunc(ctx, migrations, appliedVersions)
+}
+
+func migrateUp(runner MigrationRunner, steps int) error {
+	return withMigrationLock(runner, func(ctx context.Context, migrations []Migration, appliedVersions []int) error {
+		if len(appliedVersions) > 0 && appliedVersions[len(appliedVersions)-1] > latestMigrationVersion(migrations) {
+			return errSchemaNewer
+		}
+		for _, migration := range migrations {
+			if slices.Contains(appliedVersions, migration.Version) {
+				continue
+			}
+			if steps == 0 {
+				break
+			}
+			if err := runner.applyMigration(ctx, migration, true); err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+func migrateDown(runner MigrationRunner, steps int) error {
+	return withMigrationLock(runner, func(ctx context.Context, migrations []Migration, appliedVersions []int) error {
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := migrations[i]
+			if !slices.Contains(appliedVersions, migration.Version) {
+				continue
+			}
+			if len(migration.Down) == 0 {
+				return fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+			}
+			if err := runner.applyMigration(ctx, migration, false); err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Reverted migration %04d_%s\n", migration.Version, migration.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+func migrationStatus(runner MigrationRunner) error {
+	return withMigrationLock(runner, func(ctx context.Context, migrations []Migration, appliedVersions []int) error {
+		for _, migration := range migrations {
+			migrationState := "pending"
+			if slices.Contains(appliedVersions, migration.Version) {
+				migrationState = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", migration.Version, migration.Name, migrationState)
+		}
+		for _, appliedVersion := range appliedVersions {
+			if appliedVersion > latestMigrationVersion(migrations) {
+				fmt.Printf("%04d\tunknown\n", appliedVersion)
+			}
+		}
+		return nil
+	})
+}
+
+func checkSchemaVersion(runner MigrationRunner) error {
+	return withMigrationLock(runner, func(ctx context.Context, migrations []Migration, appliedVersions []int) error {
+		if len(appliedVersions) > 0 && appliedVersions[len(appliedVersions)-1] > latestMigrationVersion(migrations) {
+			return errSchemaNewer
+		}
+		for _, migration := range migrations {
+			if !slices.Contains(appliedVersions, migration.Version) {
+				return errSchemaPending
+			}
+		}
+		return nil
+	})
+}
+
+func isAutoMigrateEnabled() bool {
+	autoMigrate, parseErr := strconv.ParseBool(strings.TrimSpace(os.Getenv("AUTO_MIGRATE")))
+	return parseErr != nil || autoMigrate
+}
+
+func runMigrateCommand(args []string) {
+	const migrateUsage = "Usage: url-redirect migrate up [steps] | down [steps] | status"
+	if len(args) == 0 {
+		log.Fatalln(migrateUsage)
+	}
+	steps := -1
+	if args[0] == "down" {
+		steps = 1
+	}
+	if len(args) > 1 {
+		parsedSteps, parseErr := strconv.Atoi(args[1])
+		if parseErr != nil || parsedSteps < 1 {
+			log.Fatalln(migrateUsage)
+		}
+		steps = parsedSteps
+	}
+	store := connectStore()
+	defer store.Close()
+	var err error
+	switch args[0] {
+	case "up":
+		err = migrateUp(store, steps)
+	case "down":
+		err = migrateDown(store, steps)
+	case "status":
+		err = migrationStatus(store)
+	default:
+		log.Fatalln(migrateUsage)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v\n", err)
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS UrlRedirects_Analytics;
+DROP TABLE IF EXISTS UrlRedirects;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS UrlRedirects (
+    id SERIAL PRIMARY KEY,
+    path VARCHAR(29) NOT NULL UNIQUE,
+    url VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    inactive BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX IF NOT EXISTS idx_urlredirects_url ON UrlRedirects(url);
+
+CREATE TABLE IF NOT EXISTS UrlRedirects_Analytics (
+  id SERIAL PRIMARY KEY,
+  path VARCHAR(100) NOT NULL,
+  log_timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  status int NOT NULL,
+  processing_time bigint,
+  additional_headers text
+);
+CREATE INDEX IF NOT EXISTS idx_analytics_timestamp ON UrlRedirects_Analytics(log_timestamp);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS UrlRedirects_ApiKeys (
+  id SERIAL PRIMARY KEY,
+  key_id VARCHAR(16) NOT NULL UNIQUE,
+  name VARCHAR(100) NOT NULL,
+  secret_hash CHAR(64) NOT NULL,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  expires_at TIMESTAMP WITH TIME ZONE,
+  last_used_at TIMESTAMP WITH TIME ZONE,
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  rate_limit INT,
+  daily_quota INT,
+  monthly_quota INT
+);
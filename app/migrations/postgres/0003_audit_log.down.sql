@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS UrlRedirects_Audit;
+DROP FUNCTION IF EXISTS urlredirects_audit_append_only();
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS UrlRedirects_Audit (
+  id BIGSERIAL PRIMARY KEY,
+  log_timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  actor VARCHAR(255) NOT NULL,
+  source_ip VARCHAR(64),
+  request_id VARCHAR(128),
+  method VARCHAR(10) NOT NULL,
+  action VARCHAR(255) NOT NULL,
+  redirect_id INT,
+  redirect_path VARCHAR(100),
+  before_state JSONB,
+  after_state JSONB,
+  status INT NOT NULL,
+  result VARCHAR(20) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_timestamp ON UrlRedirects_Audit(log_timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_redirect_path ON UrlRedirects_Audit(redirect_path);
+CREATE OR REPLACE FUNCTION urlredirects_audit_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'UrlRedirects_Audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS urlredirects_audit_no_update ON UrlRedirects_Audit;
+CREATE TRIGGER urlredirects_audit_no_update BEFORE UPDATE OR DELETE ON UrlRedirects_Audit FOR EACH ROW EXECUTE FUNCTION urlredirects_audit_append_only();
+DROP TRIGGER IF EXISTS urlredirects_audit_no_truncate ON UrlRedirects_Audit;
+CREATE TRIGGER urlredirects_audit_no_truncate BEFORE TRUNCATE ON UrlRedirects_Audit FOR EACH STATEMENT EXECUTE FUNCTION urlredirects_audit_append_only();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_urlredirects_created_by;
+ALTER TABLE UrlRedirects DROP COLUMN IF EXISTS created_by;
+ALTER TABLE UrlRedirects DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,3 @@
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT now();
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_urlredirects_created_by ON UrlRedirects(created_by, created_at);
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects_Analytics DROP COLUMN IF EXISTS client_ip;
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects_Analytics ADD COLUMN IF NOT EXISTS client_ip VARCHAR(64);
@@ -0,0 +1,4 @@
+ALTER TABLE UrlRedirects_Audit ALTER COLUMN redirect_path TYPE VARCHAR(100);
+ALTER TABLE UrlRedirects_Analytics ALTER COLUMN path TYPE VARCHAR(100);
+ALTER TABLE UrlRedirects ALTER COLUMN url TYPE VARCHAR(100);
+ALTER TABLE UrlRedirects ALTER COLUMN path TYPE VARCHAR(29);
@@ -0,0 +1,2 @@
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS canonical_url VARCHAR(2048);
+CREATE INDEX IF NOT EXISTS idx_urlredirects_canonical_url ON UrlRedirects(canonical_url);
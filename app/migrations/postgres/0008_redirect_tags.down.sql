@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_urlredirects_tags;
+ALTER TABLE UrlRedirects DROP COLUMN IF EXISTS tags;
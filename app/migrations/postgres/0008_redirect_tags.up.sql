@@ -0,0 +1,2 @@
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_urlredirects_tags ON UrlRedirects USING GIN(tags);
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects DROP COLUMN IF EXISTS status_code;
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS status_code SMALLINT NOT NULL DEFAULT 302;
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS imported_clicks BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS title VARCHAR(255) NOT NULL DEFAULT '';
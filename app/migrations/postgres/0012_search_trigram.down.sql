@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_urlredirects_title_trgm;
+DROP INDEX IF EXISTS idx_urlredirects_url_trgm;
+DROP INDEX IF EXISTS idx_urlredirects_path_trgm;
@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_urlredirects_path_trgm ON UrlRedirects USING GIN (lower(path) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_urlredirects_url_trgm ON UrlRedirects USING GIN (lower(url) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_urlredirects_title_trgm ON UrlRedirects USING GIN (lower(title) gin_trgm_ops);
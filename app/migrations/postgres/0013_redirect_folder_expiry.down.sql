@@ -0,0 +1,2 @@
+ALTER TABLE UrlRedirects DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE UrlRedirects DROP COLUMN IF EXISTS folder;
@@ -0,0 +1,2 @@
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS folder VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE UrlRedirects ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
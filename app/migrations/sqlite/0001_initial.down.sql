@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS UrlRedirects_Audit;
+DROP TABLE IF EXISTS UrlRedirects_ApiKeys;
+DROP TABLE IF EXISTS UrlRedirects_Analytics;
+DROP TABLE IF EXISTS UrlRedirects;
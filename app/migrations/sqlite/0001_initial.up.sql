@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS UrlRedirects (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  path VARCHAR(255) NOT NULL UNIQUE,
+  url VARCHAR(2048) NOT NULL,
+  updated_at TEXT NOT NULL,
+  inactive BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TEXT NOT NULL,
+  created_by VARCHAR(255)
+);
+CREATE INDEX IF NOT EXISTS idx_urlredirects_url ON UrlRedirects(url);
+CREATE INDEX IF NOT EXISTS idx_urlredirects_created_by ON UrlRedirects(created_by, created_at);
+
+CREATE TABLE IF NOT EXISTS UrlRedirects_Analytics (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  path VARCHAR(2048) NOT NULL,
+  log_timestamp TEXT NOT NULL,
+  status INTEGER NOT NULL,
+  processing_time INTEGER,
+  additional_headers TEXT,
+  client_ip VARCHAR(64)
+);
+CREATE INDEX IF NOT EXISTS idx_analytics_timestamp ON UrlRedirects_Analytics(log_timestamp);
+
+CREATE TABLE IF NOT EXISTS UrlRedirects_ApiKeys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  key_id VARCHAR(16) NOT NULL UNIQUE,
+  name VARCHAR(100) NOT NULL,
+  secret_hash CHAR(64) NOT NULL,
+  scopes TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  expires_at TEXT,
+  last_used_at TEXT,
+  revoked_at TEXT,
+  rate_limit INTEGER,
+  daily_quota INTEGER,
+  monthly_quota INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS UrlRedirects_Audit (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  log_timestamp TEXT NOT NULL,
+  actor VARCHAR(255) NOT NULL,
+  source_ip VARCHAR(64),
+  request_id VARCHAR(128),
+  method VARCHAR(10) NOT NULL,
+  action VARCHAR(255) NOT NULL,
+  redirect_id INTEGER,
+  redirect_path VARCHAR(255),
+  before_state TEXT,
+  after_state TEXT,
+  status INTEGER NOT NULL,
+  result VARCHAR(20) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_timestamp ON UrlRedirects_Audit(log_timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_redirect_path ON UrlRedirects_Audit(redirect_path);
+CREATE TRIGGER IF NOT EXISTS urlredirects_audit_no_update BEFORE UPDATE ON UrlRedirects_Audit
+BEGIN
+  SELECT RAISE(ABORT, 'UrlRedirects_Audit is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS urlredirects_audit_no_delete BEFORE DELETE ON UrlRedirects_Audit
+BEGIN
+  SELECT RAISE(ABORT, 'UrlRedirects_Audit is append-only');
+END;
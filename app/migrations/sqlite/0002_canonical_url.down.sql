@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_urlredirects_canonical_url;
+ALTER TABLE UrlRedirects DROP COLUMN canonical_url;
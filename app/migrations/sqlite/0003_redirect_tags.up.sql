@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects ADD COLUMN tags TEXT NOT NULL DEFAULT '';
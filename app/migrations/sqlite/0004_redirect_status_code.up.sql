@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects ADD COLUMN status_code INTEGER NOT NULL DEFAULT 302;
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects ADD COLUMN imported_clicks INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE UrlRedirects ADD COLUMN title TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE UrlRedirects DROP COLUMN expires_at;
+ALTER TABLE UrlRedirects DROP COLUMN folder;
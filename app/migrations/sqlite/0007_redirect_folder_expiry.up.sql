@@ -0,0 +1,2 @@
+ALTER TABLE UrlRedirects ADD COLUMN folder TEXT NOT NULL DEFAULT '';
+ALTER TABLE UrlRedirects ADD COLUMN expires_at TEXT;
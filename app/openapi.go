@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openApiVersion = "3.0.3"
+const apiBasePath = "/redirector"
+const openApiPath = apiBasePath + "/openapi.json"
+const apiDocsPath = apiBasePath + "/docs"
+const openApiSchemaPrefix = "#/components/schemas/"
+
+var openApiPathParamRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+type ApiOperation struct {
+	Method       string
+	Path         string
+	Summary      string
+	Scope        string
+	Query        []string
+	Request      any
+	Required     []string
+	ContentTypes []string
+	Response     any
+	ResponseType string
+	StatusCode   int
+}
+
+var apiOperations = []ApiOperation{
+	{Method: http.MethodGet, Path: "/info/{id}", Summary: "Get a redirect by id", Scope: readScope, Response: Redirect{}},
+	{Method: http.MethodPost, Path: "/create", Summary: "Create a redirect", Scope: writeScope, Query: []string{"dryRun"}, Request: UrlData{}, Required: []string{"url", "path"}, Response: Redirect{}},
+	{Method: http.MethodPut, Path: "/update/{id}", Summary: "Replace a redirect", Scope: writeScope, Query: []string{"allowDuplicate", "dryRun"}, Request: Redirect{}, Required: []string{"url", "path"}, Response: Redirect{}},
+	{Method: http.MethodPatch, Path: "/fix", Summary: "Change the URL of the redirect at a path", Scope: writeScope, Query: []string{"dryRun"}, Request: UrlData{}, Required: []string{"url", "path"}, Response: Redirect{}},
+	{Method: http.MethodDelete, Path: "/disable/{id}", Summary: "Disable a redirect", Scope: writeScope, Query: []string{"dryRun"}, Response: Redirect{}},
+	{Method: http.MethodGet, Path: "/list", Summary: "List redirects", Scope: readScope, Query: []string{"status", "tag", "domain", "updatedSince", "sort", "limit", "cursor"}, Response: []Redirect{}},
+	{Method: http.MethodGet, Path: "/export", Summary: "Export redirects", Scope: readScope, Query: []string{"format", "status", "tag", "domain", "updatedSince"}, ResponseType: "text/csv"},
+	{Method: http.MethodGet, Path: "/rules/{target}", Summary: "Export redirects as web server rules", Scope: readScope, ResponseType: "text/plain"},
+	{Method: http.MethodGet, Path: "/static", Summary: "Export redirects as a static site", Scope: readScope, ResponseType: "application/zip"},
+	{Method: http.MethodPost, Path: "/generate", Summary: "Create a redirect with a generated path", Scope: writeScope, Query: []string{"dryRun"}, Request: GenerateData{}, Required: []string{"data"}, Response: Redirect{}, StatusCode: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/import", Summary: "Import redirects", Scope: writeScope, Query: []string{"format", "mode", "allowDuplicate", "dryRun"}, ContentTypes: []string{"text/csv", "application/json", "application/x-ndjson"}, Response: ImportReport{}},
+	{Method: http.MethodPost, Path: "/batch", Summary: "Apply an action to many redirects", Scope: writeScope, Query: []string{"dryRun", "status", "tag", "domain", "updatedSince"}, Request: BatchRequest{}, Required: []string{"action"}, Response: BatchReport{}},
+	{Method: http.MethodGet, Path: "/v2/redirects", Summary: "List redirects or look one up by path", Scope: readScope, Query: []string{"path", "status", "tag", "domain", "updatedSince", "sort", "limit", "cursor"}, Response: []RedirectResource{}},
+	{Method: http.MethodPost, Path: "/v2/redirects", Summary: "Create a redirect", Scope: writeScope, Query: []string{"allowDuplicate", "dryRun"}, Request: RedirectInput{}, Required: []string{"url", "path"}, Response: RedirectResource{}, StatusCode: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/v2/redirects/{id}", Summary: "Get a redirect", Scope: readScope, Response: RedirectResource{}},
+	{Method: http.MethodPatch, Path: "/v2/redirects/{id}", Summary: "Update a redirect with a JSON Merge Patch", Scope: writeScope, Query: []string{"allowDuplicate", "dryRun"}, Request: RedirectInput{}, ContentTypes: []string{mergePatchContentType, "application/json"}, Response: RedirectResource{}},
+	{Method: http.MethodDelete, Path: "/v2/redirects/{id}", Summary: "Disable a redirect", Scope: writeScope, Query: []string{"dryRun"}, Response: RedirectResource{}},
+	{Method: http.MethodPost, Path: "/search", Summary: "Search redirects by path", Scope: readScope, Query: []string{"status", "tag", "domain", "updatedSince", "sort", "limit", "cursor"}, Request: OpsData{}, Response: []Redirect{}},
+	{Method: http.MethodPost, Path: "/find", Summary: "Ranked search across path, URL, title and tags", Scope: readScope, Request: SearchQuery{}, Response: SearchResponse{}},
+	{Method: http.MethodPost, Path: "/check", Summary: "Find the redirect for a URL", Scope: readScope, Request: OpsData{}, Required: []string{"data"}, Response: Redirect{}},
+	{Method: http.MethodPost, Path: "/stats", Summary: "Get analytics for a period", Scope: statsScope, Request: StatsTime{}, Response: LogStatsData{}},
+	{Method: http.MethodPost, Path: "/keys", Summary: "Create an API key", Scope: adminScope, Request: ApiKeyData{}, Required: []string{"name", "scopes"}, Response: ApiKey{}},
+	{Method: http.MethodGet, Path: "/keys", Summary: "List API keys", Scope: adminScope, Response: []ApiKey{}},
+	{Method: http.MethodPost, Path: "/audit", Summary: "List audit logs", Scope: adminScope, Query: []string{"page"}, Request: AuditQuery{}, Response: []AuditLog{}},
+	{Method: http.MethodDelete, Path: "/keys/{keyId}", Summary: "Revoke an API key", Scope: adminScope, Response: ApiKey{}},
+}
+
+var apiParameterSchemas = map[string]*OpenApiSchema{
+	"id":             {Type: "integer", Minimum: 1},
+	"dryRun":         {Type: "boolean"},
+	"allowDuplicate": {Type: "boolean"},
+	"limit":          {Type: "integer", Minimum: 1, Maximum: maxPageLimit},
+	"page":           {Type: "integer"},
+	"status":         {Type: "string", Enum: []string{"all", "active", "inactive"}},
+	"mode":           {Type: "string", Enum: importModes},
+	"sort":           {Type: "string", Pattern: "^-?(" + strings.Join(redirectSorts, "|") + ")$"},
+	"updatedSince":   {Type: "string", Pattern: `^(\d+|\d{4}-\d{2}-\d{2}T.+)$`},
+}
+
+type OpenApiSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Minimum              int                       `json:"minimum,omitempty"`
+	Maximum              int                       `json:"maximum,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	ReadOnly             bool                      `json:"readOnly,omitempty"`
+	Properties           map[string]*OpenApiSchema `json:"properties,omitempty"`
+	Items                *OpenApiSchema            `json:"items,omitempty"`
+	AdditionalProperties any                       `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AllOf                []*OpenApiSchema          `json:"allOf,omitempty"`
+}
+
+type OpenApiMediaType struct {
+	Schema *OpenApiSchema `json:"schema,omitempty"`
+}
+
+type OpenApiParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenApiSchema `json:"schema"`
+}
+
+type OpenApiRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenApiMediaType `json:"content"`
+}
+
+type OpenApiResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenApiMediaType `json:"content,omitempty"`
+}
+
+type OpenApiOperation struct {
+	OperationId string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Scope       string                     `json:"x-required-scope,omitempty"`
+	Parameters  []OpenApiParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenApiRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenApiResponse `json:"responses"`
+}
+
+type OpenApiInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenApiComponents struct {
+	Schemas         map[string]*OpenApiSchema `json:"schemas"`
+	SecuritySchemes map[string]any            `json:"securitySchemes"`
+}
+
+type OpenApiDocument struct {
+	OpenApi    string                                  `json:"openapi"`
+	Info       OpenApiInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenApiOperation `json:"paths"`
+	Components OpenApiComponents                       `json:"components"`
+	Security   []map[string][]string                   `json:"security"`
+}
+
+var openApiDocument = buildOpenApiDocument(apiOperations)
+
+func (document *OpenApiDocument) typeSchema(t reflect.Type) *OpenApiSchema {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return &OpenApiSchema{Type: "string", Format: "date-time"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return &OpenApiSchema{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		elemSchema := document.typeSchema(t.Elem())
+		if len(elemSchema.Ref) > 0 {
+			return &OpenApiSchema{AllOf: []*OpenApiSchema{elemSchema}, Nullable: true}
+		}
+		elemSchema.Nullable = true
+		return elemSchema
+	case reflect.Bool:
+		return &OpenApiSchema{Type: "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenApiSchema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenApiSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenApiSchema{Type: "number"}
+	case reflect.String:
+		return &OpenApiSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &OpenApiSchema{Type: "array", Items: document.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &OpenApiSchema{Type: "object", AdditionalProperties: document.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if _, isDefined := document.Components.Schemas[t.Name()]; !isDefined {
+			structSchema := &OpenApiSchema{Type: "object", Properties: map[string]*OpenApiSchema{}, AdditionalProperties: false}
+			document.Components.Schemas[t.Name()] = structSchema
+			document.structProperties(t, structSchema.Properties)
+		}
+		return &OpenApiSchema{Ref: openApiSchemaPrefix + t.Name()}
+	}
+	return &OpenApiSchema{}
+}
+
+func (document *OpenApiDocument) structProperties(t reflect.Type, properties map[string]*OpenApiSchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case !field.IsExported() || jsonName == "-":
+		case field.Anonymous && len(jsonName) == 0 && field.Type.Kind() == reflect.Struct:
+			document.structProperties(field.Type, properties)
+		case len(jsonName) == 0:
+			properties[field.Name] = document.typeSchema(field.Type)
+		default:
+			properties[jsonName] = document.typeSchema(field.Type)
+		}
+	}
+}
+
+func (document *OpenApiDocument) resolveSchema(schema *OpenApiSchema) *OpenApiSchema {
+	for schema != nil && len(schema.Ref) > 0 {
+		schema = document.Components.Schemas[strings.TrimPrefix(schema.Ref, openApiSchemaPrefix)]
+	}
+	return schema
+}
+
+func (document *OpenApiDocument) mergePatchSchema(schema *OpenApiSchema, resourceSchema *OpenApiSchema) *OpenApiSchema {
+	name := strings.TrimPrefix(schema.Ref, openApiSchemaPrefix) + "Patch"
+	if _, isDefined := document.Components.Schemas[name]; !isDefined {
+		patchSchema := *document.resolveSchema(schema)
+		patchSchema.Properties = map[string]*OpenApiSchema{}
+		for property, propertySchema := range document.resolveSchema(schema).Properties {
+			nullableSchema := *propertySchema
+			nullableSchema.Nullable = true
+			patchSchema.Properties[property] = &nullableSchema
+		}
+		if resourceSchema = document.resolveSchema(resourceSchema); resourceSchema != nil {
+			for property, propertySchema := range resourceSchema.Properties {
+				if _, isEditable := patchSchema.Properties[property]; !isEditable {
+					readOnlySchema := *propertySchema
+					readOnlySchema.ReadOnly = true
+					patchSchema.Properties[property] = &readOnlySchema
+				}
+			}
+		}
+		document.Components.Schemas[name] = &patchSchema
+	}
+	return &OpenApiSchema{Ref: openApiSchemaPrefix + name}
+}
+
+func apiParameterSchema(name string) *OpenApiSchema {
+	if schema, isTyped := apiParameterSchemas[name]; isTyped {
+		return schema
+	}
+	return &OpenApiSchema{Type: "string"}
+}
+
+func apiOperationId(operation ApiOperation) string {
+	operationId := strings.ToLower(operation.Method)
+	for _, segment := range strings.Split(strings.Trim(operation.Path, "/"), "/") {
+		segment = strings.Trim(segment, "{}")
+		operationId += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return operationId
+}
+
+func buildOpenApiDocument(operations []ApiOperation) *OpenApiDocument {
+	document := &OpenApiDocument{
+		OpenApi:  openApiVersion,
+		Info:     OpenApiInfo{Title: "URL Redirect API", Version: "2"},
+		Paths:    map[string]map[string]*OpenApiOperation{},
+		Security: []map[string][]string{{"apiKey": {}}, {"bearerAuth": {}}},
+		Components: OpenApiComponents{
+			Schemas: map[string]*OpenApiSchema{},
+			SecuritySchemes: map[string]any{
+				"apiKey":     map[string]string{"type": "apiKey", "in": "header", "name": "x-url-redirect-token"},
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+	errorSchema := document.typeSchema(reflect.TypeOf(ApiError{}))
+	document.resolveSchema(errorSchema).Properties["code"] = &OpenApiSchema{Type: "string", Enum: apiErrorCodes}
+	errorResponse := OpenApiResponse{Description: "Error", Content: map[string]OpenApiMediaType{"application/json": {Schema: errorSchema}}}
+	for _, operation := range operations {
+		openApiOperation := &OpenApiOperation{OperationId: apiOperationId(operation), Summary: operation.Summary, Scope: operation.Scope, Responses: map[string]OpenApiResponse{"default": errorResponse}}
+		for _, pathParam := range openApiPathParamRegex.FindAllStringSubmatch(operation.Path, -1) {
+			openApiOperation.Parameters = append(openApiOperation.Parameters, OpenApiParameter{Name: pathParam[1], In: "path", Required: true, Schema: apiParameterSchema(pathParam[1])})
+		}
+		for _, queryParam := range operation.Query {
+			openApiOperation.Parameters = append(openApiOperation.Parameters, OpenApiParameter{Name: queryParam, In: "query", Schema: apiParameterSchema(queryParam)})
+		}
+		contentTypes := operation.ContentTypes
+		if len(contentTypes) == 0 && operation.Request != nil {
+			contentTypes = []string{"application/json"}
+		}
+		if len(contentTypes) > 0 {
+			openApiOperation.RequestBody = &OpenApiRequestBody{Required: true, Content: map[string]OpenApiMediaType{}}
+		}
+		for _, contentType := range contentTypes {
+			var requestSchema *OpenApiSchema
+			switch {
+			case operation.Request == nil:
+			case slices.Contains(contentTypes, mergePatchContentType):
+				var resourceSchema *OpenApiSchema
+				if operation.Response != nil {
+					resourceSchema = document.typeSchema(reflect.TypeOf(operation.Response))
+				}
+				requestSchema = document.mergePatchSchema(document.typeSchema(reflect.TypeOf(operation.Request)), resourceSchema)
+			case len(operation.Required) > 0:
+				requestSchema = &OpenApiSchema{AllOf: []*OpenApiSchema{document.typeSchema(reflect.TypeOf(operation.Request)), {Required: operation.Required}}}
+			default:
+				requestSchema = document.typeSchema(reflect.TypeOf(operation.Request))
+			}
+			openApiOperation.RequestBody.Content[contentType] = OpenApiMediaType{Schema: requestSchema}
+		}
+		statusCode := operation.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		successResponse := OpenApiResponse{Description: http.StatusText(statusCode)}
+		switch {
+		case operation.Response != nil:
+			successResponse.Content = map[string]OpenApiMediaType{"application/json": {Schema: document.typeSchema(reflect.TypeOf(operation.Response))}}
+		case len(operation.ResponseType) > 0:
+			successResponse.Content = map[string]OpenApiMediaType{operation.ResponseType: {Schema: &OpenApiSchema{Type: "string", Format: "binary"}}}
+		}
+		openApiOperation.Responses[strconv.Itoa(statusCode)] = successResponse
+		path := apiBasePath + operation.Path
+		if document.Paths[path] == nil {
+			document.Paths[path] = map[string]*OpenApiOperation{}
+		}
+		document.Paths[path][strings.ToLower(operation.Method)] = openApiOperation
+	}
+	return document
+}
+
+func (document *OpenApiDocument) operation(method string, path string) *OpenApiOperation {
+	return document.Paths[apiBasePath+path][strings.ToLower(method)]
+}
+
+func openApiSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(toJson(openApiDocument))
+}
+
+type ApiDocsOperation struct {
+	Method    string
+	Path      string
+	Operation *OpenApiOperation
+	Request   string
+	Response  string
+}
+
+type ApiDocsSchema struct {
+	Name   string
+	Schema string
+}
+
+var apiDocsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>body{font-family:sans-serif;max-width:960px;margin:auto;padding:1em}h3{font-family:monospace}.method{display:inline-block;min-width:5em}pre{background:#f4f4f4;padding:.5em;overflow:auto}</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Machine readable document: <a href="{{.SpecPath}}">{{.SpecPath}}</a></p>
+{{range .Operations}}<section id="{{.Operation.OperationId}}">
+<h3><span class="method">{{.Method}}</span>{{.Path}}</h3>
+<p>{{.Operation.Summary}}{{if .Operation.Scope}} (scope: {{.Operation.Scope}}){{end}}</p>
+{{if .Operation.Parameters}}<p>Parameters: {{range $i, $p := .Operation.Parameters}}{{if $i}}, {{end}}<code>{{$p.Name}}</code> ({{$p.In}}){{end}}</p>{{end}}
+{{if .Request}}<p>Request</p><pre>{{.Request}}</pre>{{end}}
+{{if .Response}}<p>Response</p><pre>{{.Response}}</pre>{{end}}
+</section>
+{{end}}<h2>Schemas</h2>
+{{range .Schemas}}<h3 id="{{.Name}}">{{.Name}}</h3>
+<pre>{{.Schema}}</pre>
+{{end}}</body>
+</html>
+`))
+
+func indentedJson(data any) string {
+	dataJson, _ := json.MarshalIndent(data, "", "  ")
+	return string(dataJson)
+}
+
+func apiDocs(w http.ResponseWriter, r *http.Request) {
+	var operations []ApiDocsOperation
+	for _, operation := range apiOperations {
+		openApiOperation := openApiDocument.operation(operation.Method, operation.Path)
+		docsOperation := ApiDocsOperation{Method: operation.Method, Path: apiBasePath + operation.Path, Operation: openApiOperation}
+		if openApiOperation.RequestBody != nil {
+			docsOperation.Request = indentedJson(openApiOperation.RequestBody.Content)
+		}
+		for statusCode, response := range openApiOperation.Responses {
+			if statusCode != "default" {
+				docsOperation.Response = statusCode + " " + indentedJson(response.Content)
+			}
+		}
+		operations = append(operations, docsOperation)
+	}
+	var schemas []ApiDocsSchema
+	for name, schema := range openApiDocument.Components.Schemas {
+		schemas = append(schemas, ApiDocsSchema{Name: name, Schema: indentedJson(schema)})
+	}
+	slices.SortFunc(schemas, func(x, y ApiDocsSchema) int {
+		return strings.Compare(x.Name, y.Name)
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	apiDocsTemplate.Execute(w, map[string]any{"Title": openApiDocument.Info.Title, "SpecPath": openApiPath, "Operations": operations, "Schemas": schemas})
+}
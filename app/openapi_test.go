@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenApiOperationsMatchRoutes(t *testing.T) {
+	var routes []string
+	chi.Walk(initRouter(newMemoryStore()), func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		apiRoute, isApiRoute := strings.CutPrefix(route, apiBasePath)
+		if !isApiRoute || route == openApiPath || route == apiDocsPath {
+			return nil
+		}
+		routes = append(routes, method+" "+apiRoute)
+		if openApiDocument.operation(method, apiRoute) == nil {
+			t.Errorf("OpenAPI document is missing %s %s", method, route)
+		}
+		return nil
+	})
+	for _, operation := range apiOperations {
+		if !slices.Contains(routes, operation.Method+" "+operation.Path) {
+			t.Errorf("OpenAPI document has no route for %s %s", operation.Method, operation.Path)
+		}
+	}
+}
+
+func TestMergePatchSchemaLeavesReadOnlyFieldsToHandler(t *testing.T) {
+	requestBody := openApiDocument.operation(http.MethodPatch, "/v2/redirects/{id}").RequestBody
+	schema := requestBody.Content[mergePatchContentType].Schema
+	for _, field := range readOnlyRedirectFields {
+		if validationErr := openApiDocument.validateValue(schema, map[string]any{field: "value"}, ""); validationErr != nil {
+			t.Errorf("%s rejected by schema validation: %v", field, validationErr)
+		}
+	}
+	if validationErr := openApiDocument.validateValue(schema, map[string]any{"bogus": "value"}, ""); validationErr == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestApiErrorSchemaPublishesCodes(t *testing.T) {
+	errorSchema := openApiDocument.resolveSchema(openApiDocument.operation(http.MethodGet, "/list").Responses["default"].Content["application/json"].Schema)
+	if codeSchema := errorSchema.Properties["code"]; !slices.Equal(codeSchema.Enum, apiErrorCodes) {
+		t.Errorf("ApiError code enum = %v", codeSchema.Enum)
+	}
+}
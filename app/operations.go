@@ -1,86 +1,66 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func listall(db *pgxpool.Pool) http.HandlerFunc {
+func listall(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var responseData []Redirect
-		page, pageErr := strconv.Atoi(r.URL.Query().Get("page"))
-		if pageErr != nil {
-			page = 0
-		}
-		min, max := page, page+pageLimit
-		rows, db_err := db.Query(context.Background(), "SELECT id, path, url, updated_at::TEXT, inactive FROM UrlRedirects WHERE id>$1 AND id<=$2 LIMIT $3", min, max, pageLimit)
-		if db_err != nil {
-			http.Error(w, notFoundMessage, http.StatusInternalServerError)
+		redirectQuery, isQueryValid := parseRedirectQuery(r)
+		if !isQueryValid {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
 			return
 		}
-		defer rows.Close()
-		for rows.Next() {
-			var temp Redirect
-			rowErr := rows.Scan(&temp.Id, &temp.Path, &temp.Url, &temp.LastUpdated, &temp.Inactive)
-			if rowErr == nil {
-				responseData = append(responseData, temp)
-			}
-		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(toJson(responseData))
+		writeRedirectPage(w, r, store, redirectQuery)
 	})
 }
 
-func searchPath(db *pgxpool.Pool) http.HandlerFunc {
+func searchPath(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var requestData OpsData
 		err := json.NewDecoder(r.Body).Decode(&requestData)
-		if err != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+		redirectQuery, isQueryValid := parseRedirectQuery(r)
+		if err != nil || !isQueryValid {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		var responseData []Redirect
-		pathMatchPattern := "%" + requestData.Data + "%"
-		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-		rows, db_err := db.Query(context.Background(), "SELECT id, path, url, updated_at::TEXT, inactive FROM UrlRedirects WHERE path ILIKE $1 AND inactive=$2 LIMIT $3 OFFSET $4", pathMatchPattern, false, pageLimit, page)
-		if db_err != nil {
-			http.Error(w, dbError, http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var temp Redirect
-			rowErr := rows.Scan(&temp.Id, &temp.Path, &temp.Url, &temp.LastUpdated, &temp.Inactive)
-			if rowErr == nil {
-				responseData = append(responseData, temp)
-			}
+		if len(r.URL.Query().Get("status")) == 0 {
+			isInactive := false
+			redirectQuery.Inactive = &isInactive
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(toJson(responseData))
+		redirectQuery.Search = requestData.Data
+		writeRedirectPage(w, r, store, redirectQuery)
 	})
 }
 
-func redirectExists(db *pgxpool.Pool) http.HandlerFunc {
+func redirectExists(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var requestData OpsData
 		err := json.NewDecoder(r.Body).Decode(&requestData)
 		if err != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		var responseData Redirect
-		db_err := db.QueryRow(context.Background(), "SELECT id, path, url, updated_at::TEXT, inactive FROM UrlRedirects WHERE url=$1 LIMIT $2", requestData.Data, dbLimit).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
+		canonicalUrl, isCanonicalUrlValid := canonicalizeUrl(requestData.Data)
+		if !isCanonicalUrlValid {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		responseData, db_err := store.GetRedirectUsingCanonicalUrl(r.Context(), canonicalUrl)
+		if errors.Is(db_err, errNotFound) {
+			writeApiError(w, r, http.StatusNotFound, notFoundCode, notExistMessage)
+			return
+		}
 		if db_err != nil {
-			http.Error(w, dbError, http.StatusPreconditionFailed)
+			log.Println("redirectExists -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -88,77 +68,108 @@ func redirectExists(db *pgxpool.Pool) http.HandlerFunc {
 	})
 }
 
-func generateRedirect(db *pgxpool.Pool) http.HandlerFunc {
+func generateRedirect(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var requestData OpsData
+		var requestData GenerateData
 		err := json.NewDecoder(r.Body).Decode(&requestData)
 		validUrl, isUrlValid := validateAndFormatURL(requestData.Data)
+		canonicalUrl, isCanonicalUrlValid := canonicalizeUrl(requestData.Data)
+		pathStrategy := getPathStrategy()
+		if len(requestData.Strategy) > 0 {
+			pathStrategy = strings.ToLower(strings.TrimSpace(requestData.Strategy))
+		}
+		strategy, isStrategyValid := pathStrategies[pathStrategy]
+		pathLength := requestData.Length
+		if pathLength == 0 && isStrategyValid {
+			pathLength = getPathLength(pathStrategy)
+		}
 		w.Header().Set("Content-Type", "application/json")
-		if !isUrlValid || err != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+		var validationErr error
+		switch {
+		case err != nil:
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
 			return
+		case !isUrlValid || !isCanonicalUrlValid:
+			validationErr = &FieldError{Field: "data", Message: "must be an absolute URL"}
+		case !isStrategyValid:
+			validationErr = &FieldError{Field: "strategy", Message: "must be random, unambiguous, sequential, hashid or words"}
+		case pathLength < 1 || pathLength > strategy.maxLength:
+			validationErr = &FieldError{Field: "length", Message: "must be between 1 and " + strconv.Itoa(strategy.maxLength)}
+		case !isRedirectStatusValid(requestData.StatusCode):
+			validationErr = &FieldError{Field: "statusCode", Message: "must be 301, 302, 303, 307 or 308"}
+		case !isTitleValid(requestData.Title):
+			validationErr = &FieldError{Field: "title", Message: "must be at most " + strconv.Itoa(maxTitleLength) + " characters"}
 		}
-		generatedShortPath := generateShortRedirectPath()
-		_, duplicateErr := getRedirectUsingPath(generatedShortPath, db)
-		urlExists := doesUrlExists(validUrl, db)
-		if urlExists || duplicateErr == nil {
-			http.Error(w, alreadyExistMessage, http.StatusPreconditionFailed)
+		if validationErr != nil {
+			writeFieldError(w, r, validationErr)
 			return
 		}
 		var responseData Redirect
-		db_err := db.QueryRow(context.Background(), "INSERT INTO UrlRedirects (path, url, updated_at) VALUES ($1,$2,now()) RETURNING id, path, url, updated_at::TEXT, inactive", generatedShortPath, validUrl).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
-		if db_err != nil {
-			http.Error(w, dbError, http.StatusInternalServerError)
+		isReused := false
+		db_err := runRedirectMutation(r, store, func(redirectStore RedirectStore) error {
+			existingRedirect, urlErr := redirectStore.LockCanonicalUrl(r.Context(), canonicalUrl)
+			switch {
+			case urlErr != nil && !errors.Is(urlErr, errNotFound):
+				return urlErr
+			case urlErr != nil || requestData.AllowDuplicate:
+			case requestData.Reuse && !existingRedirect.Inactive:
+				responseData, isReused = existingRedirect, true
+				return nil
+			case !requestData.Reuse:
+				return errDuplicateUrl
+			}
+			insertErr := errConflict
+			for attempt := 0; attempt < generateAttempts && errors.Is(insertErr, errConflict); attempt++ {
+				generatedRedirect, generateErr := generateRedirectPath(r.Context(), redirectStore, pathStrategy, pathLength)
+				if generateErr != nil {
+					insertErr = generateErr
+					continue
+				}
+				generatedRedirect.Url, generatedRedirect.CanonicalUrl, generatedRedirect.Tags = validUrl, canonicalUrl, normalizeTags(requestData.Tags)
+				generatedRedirect.StatusCode = redirectStatus(requestData.StatusCode, 0)
+				generatedRedirect.Title = mergeTitle(requestData.Title, "")
+				responseData, insertErr = insertRedirectWithinQuota(r.Context(), redirectStore, generatedRedirect, callerKeyId(r))
+			}
+			return insertErr
+		})
+		var quotaErr *QuotaError
+		switch {
+		case isReused:
+			writeRedirectMutation(w, r, responseData, responseData, http.StatusOK)
+			return
+		case errors.As(db_err, &quotaErr):
+			writeQuotaError(w, r, quotaErr)
+			return
+		case errors.Is(db_err, errDuplicateUrl):
+			writeApiError(w, r, http.StatusConflict, alreadyExistsCode, alreadyExistMessage)
+			return
+		case errors.Is(db_err, errConflict):
+			writeApiError(w, r, http.StatusConflict, generateConflictCode, generateConflictMessage)
+			return
+		case db_err != nil:
+			log.Println("generateRedirect -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(toJson(responseData))
+		writeRedirectMutation(w, r, Redirect{}, responseData, http.StatusCreated)
 	})
 }
 
-func stats(db *pgxpool.Pool) http.HandlerFunc {
+func stats(store Store) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var statsQueryPeriod StatsTime
 		err := json.NewDecoder(r.Body).Decode(&statsQueryPeriod)
 		startTime := time.Unix(statsQueryPeriod.Start, 0)
 		endTime := time.Unix(statsQueryPeriod.End, 0)
 		if err != nil {
-			http.Error(w, badRequest, http.StatusBadRequest)
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
 			return
 		}
-		statsData := LogStatsData{}
-		queryResults, queryErr := db.Query(context.Background(),
-			`SELECT 'path' AS col, path AS stat_key, count(id) AS stat_count FROM urlredirects_analytics WHERE log_timestamp BETWEEN TO_TIMESTAMP($1) AND TO_TIMESTAMP($2) GROUP BY path UNION ALL 
-			 SELECT 'status' AS col, CAST(status AS VARCHAR) AS stat_key, count(id) AS stat_count FROM urlredirects_analytics WHERE log_timestamp BETWEEN TO_TIMESTAMP($1) AND TO_TIMESTAMP($2) GROUP BY status UNION ALL 
-			 SELECT 'time' AS col, CAST(status AS VARCHAR) AS stat_key, CAST(avg(processing_time) AS INTEGER) AS stat_count FROM urlredirects_analytics WHERE log_timestamp BETWEEN TO_TIMESTAMP($1) AND TO_TIMESTAMP($2) GROUP BY status;`,
-			startTime.Unix(), endTime.Unix())
+		statsData, queryErr := store.GetStats(r.Context(), startTime, endTime)
 		if queryErr != nil {
-			http.Error(w, internalError, http.StatusInternalServerError)
+			writeApiError(w, r, http.StatusInternalServerError, internalErrorCode, internalError)
 			return
 		}
-		defer queryResults.Close()
-		for queryResults.Next() {
-			var dataItem LogQueryData
-			var dataKey string
-			var statKey pgtype.Text
-			rowErr := queryResults.Scan(&dataKey, &statKey, &dataItem.StatCount)
-			if rowErr != nil {
-				continue
-			}
-			if len(strings.TrimSpace(statKey.String)) <= 0 {
-				dataItem.StatKey = "Other"
-			} else {
-				dataItem.StatKey = strings.TrimSpace(statKey.String)
-			}
-			switch {
-			case dataKey == "path":
-				statsData.Path = append(statsData.Path, dataItem)
-			case dataKey == "status":
-				statsData.Status = append(statsData.Status, dataItem)
-			case dataKey == "time":
-				statsData.Time = append(statsData.Time, dataItem)
-			}
-		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write(toJson(statsData))
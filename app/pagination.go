@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var redirectSorts = []string{sortById, sortByPath, sortByUpdatedAt, sortByCreatedAt}
+
+type PageCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Value      string `json:"v,omitempty"`
+	Id         int    `json:"i"`
+}
+
+func encodePageCursor(query RedirectQuery, redirect Redirect) string {
+	pageCursor := PageCursor{Sort: query.Sort, Descending: query.Descending, Value: redirect.sortValue(query.Sort), Id: redirect.Id}
+	return base64.RawURLEncoding.EncodeToString(toJson(pageCursor))
+}
+
+func decodePageCursor(cursor string) (PageCursor, bool) {
+	var pageCursor PageCursor
+	cursorBytes, decodeErr := base64.RawURLEncoding.DecodeString(cursor)
+	if decodeErr != nil || json.Unmarshal(cursorBytes, &pageCursor) != nil || pageCursor.Id <= 0 {
+		return pageCursor, false
+	}
+	return pageCursor, true
+}
+
+func parseRedirectQuery(r *http.Request) (RedirectQuery, bool) {
+	queryParams := r.URL.Query()
+	filter, isFilterValid := parseRedirectFilter(r)
+	redirectQuery := RedirectQuery{RedirectFilter: filter, Sort: sortById, Limit: pageLimit}
+	if !isFilterValid {
+		return redirectQuery, false
+	}
+	if sort := strings.TrimSpace(queryParams.Get("sort")); len(sort) > 0 {
+		redirectQuery.Descending = strings.HasPrefix(sort, "-")
+		redirectQuery.Sort = strings.TrimPrefix(sort, "-")
+	}
+	isSortValid := false
+	for _, redirectSort := range redirectSorts {
+		if strings.EqualFold(redirectQuery.Sort, redirectSort) {
+			redirectQuery.Sort, isSortValid = redirectSort, true
+		}
+	}
+	if limit := queryParams.Get("limit"); len(limit) > 0 {
+		var limitErr error
+		if redirectQuery.Limit, limitErr = strconv.Atoi(limit); limitErr != nil || redirectQuery.Limit < 1 || redirectQuery.Limit > maxPageLimit {
+			return redirectQuery, false
+		}
+	}
+	if cursor := queryParams.Get("cursor"); len(cursor) > 0 {
+		pageCursor, isCursorValid := decodePageCursor(cursor)
+		if !isCursorValid || pageCursor.Sort != redirectQuery.Sort || pageCursor.Descending != redirectQuery.Descending {
+			return redirectQuery, false
+		}
+		redirectQuery.AfterValue, redirectQuery.AfterId = pageCursor.Value, pageCursor.Id
+	}
+	return redirectQuery, isSortValid
+}
+
+func loadRedirectPage(w http.ResponseWriter, r *http.Request, store Store, redirectQuery RedirectQuery) ([]Redirect, bool) {
+	pageSize := redirectQuery.Limit
+	redirectQuery.Limit++
+	responseData, db_err := store.ListRedirects(r.Context(), redirectQuery)
+	if db_err != nil {
+		log.Println("loadRedirectPage -> ", db_err.Error())
+		writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+		return nil, false
+	}
+	totalCount, db_err := store.CountRedirects(r.Context(), redirectQuery.RedirectFilter)
+	if db_err != nil {
+		log.Println("loadRedirectPage -> ", db_err.Error())
+		writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+		return nil, false
+	}
+	if len(responseData) > pageSize {
+		responseData = responseData[:pageSize]
+		w.Header().Set("X-Next-Cursor", encodePageCursor(redirectQuery, responseData[pageSize-1]))
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(totalCount))
+	return responseData, true
+}
+
+func writeRedirectPage(w http.ResponseWriter, r *http.Request, store Store, redirectQuery RedirectQuery) {
+	responseData, isLoaded := loadRedirectPage(w, r, store, redirectQuery)
+	if !isLoaded {
+		return
+	}
+	if responseData == nil {
+		responseData = []Redirect{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(toJson(responseData))
+}
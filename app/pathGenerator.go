@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const randomStrategy = "random"
+const unambiguousStrategy = "unambiguous"
+const sequentialStrategy = "sequential"
+const hashidStrategy = "hashid"
+const wordsStrategy = "words"
+
+const base62Alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+const unambiguousAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+const hashidSeparators = "cfhistuCFHISTU"
+
+var pathAlphabetRegex = regexp.MustCompile(`^[A-Za-z0-9_~.-]+$`)
+
+var envPathStrategy = os.Getenv("PATH_STRATEGY")
+var envPathLength = os.Getenv("PATH_LENGTH")
+var envPathAlphabet = os.Getenv("PATH_ALPHABET")
+var envHashidSalt = os.Getenv("PATH_HASHID_SALT")
+var envProfanityWords = os.Getenv("PATH_BLOCKED_WORDS")
+
+type PathStrategy struct {
+	defaultLength int
+	maxLength     int
+	usesId        bool
+	generate      func(id int, length int) string
+}
+
+var pathStrategies = map[string]PathStrategy{
+	randomStrategy:      {defaultLength: 9, maxLength: 64, generate: randomAlphabetPath(pathAlphabet())},
+	unambiguousStrategy: {defaultLength: 9, maxLength: 64, generate: randomAlphabetPath(unambiguousAlphabet)},
+	sequentialStrategy:  {defaultLength: 1, maxLength: 64, usesId: true, generate: sequentialPath},
+	hashidStrategy:      {defaultLength: 6, maxLength: 64, usesId: true, generate: hashidPath},
+	wordsStrategy:       {defaultLength: 3, maxLength: 8, generate: wordsPath},
+}
+
+var pathWords = strings.Fields(`
+	amber apple arrow aspen atlas autumn bamboo basil beacon birch bison blossom breeze brook cactus canyon
+	cedar cherry cliff clover cobalt comet coral cosmos cotton crane crystal daisy delta dune eagle echo
+	ember falcon fern fjord flint forest fox galaxy garnet glacier harbor hazel heron honey horizon indigo
+	iris island ivory jade jasmine juniper kayak kiwi lagoon lantern lemon lilac lotus lunar maple marble
+	meadow mango meteor mint mist nectar nova oak ocean olive onyx orbit orchid otter panda pearl pebble
+	pepper pine planet plum polar prairie quartz quill rain raven reef river robin ruby saffron sage sapphire
+	sequoia shadow sierra silver sky snow solar sparrow spruce storm summit sunset swan thistle thunder tiger
+	topaz tulip tundra valley velvet violet walnut willow winter zebra zephyr zinnia
+`)
+
+var profanityWords = append(strings.Fields(`
+	anal anus arse ass bastard bitch bollock boner boob butt clit cock coon crap cum cunt damn dick dildo
+	dyke fag fuck hell homo jizz kike nazi nigga nigger piss poop porn prick pube pussy queer rape scrotum
+	sex shit slut spic tit twat vagina wank whore
+`), strings.Fields(strings.ReplaceAll(strings.ToLower(envProfanityWords), ",", " "))...)
+
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "@", "a", "$", "s")
+
+func pathAlphabet() string {
+	customAlphabet := strings.TrimSpace(envPathAlphabet)
+	if len(customAlphabet) == 0 {
+		return base62Alphabet
+	}
+	if len(customAlphabet) < 2 || !pathAlphabetRegex.MatchString(customAlphabet) {
+		log.Println("Invalid Path Alphabet:", customAlphabet)
+		return base62Alphabet
+	}
+	return customAlphabet
+}
+
+func getPathStrategy() string {
+	pathStrategy := strings.ToLower(strings.TrimSpace(envPathStrategy))
+	if _, isValid := pathStrategies[pathStrategy]; !isValid {
+		return randomStrategy
+	}
+	return pathStrategy
+}
+
+func getPathLength(pathStrategy string) int {
+	pathLength, parseErr := strconv.Atoi(strings.TrimSpace(envPathLength))
+	if parseErr != nil || pathStrategy != getPathStrategy() || pathLength < 1 || pathLength > pathStrategies[pathStrategy].maxLength {
+		return pathStrategies[pathStrategy].defaultLength
+	}
+	return pathLength
+}
+
+func randomIndex(n int) int {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(index.Int64())
+}
+
+func randomAlphabetPath(alphabet string) func(int, int) string {
+	alphabetRunes := []rune(alphabet)
+	return func(id int, length int) string {
+		shortPath := make([]rune, length)
+		for i := range shortPath {
+			shortPath[i] = alphabetRunes[randomIndex(len(alphabetRunes))]
+		}
+		return string(shortPath)
+	}
+}
+
+func encodeAlphabet(id int, alphabet string) string {
+	encoded := []byte{}
+	for {
+		encoded = append([]byte{alphabet[id%len(alphabet)]}, encoded...)
+		id = id / len(alphabet)
+		if id == 0 {
+			return string(encoded)
+		}
+	}
+}
+
+func sequentialPath(id int, length int) string {
+	encoded := encodeAlphabet(id, base62Alphabet)
+	if len(encoded) < length {
+		encoded = strings.Repeat(base62Alphabet[:1], length-len(encoded)) + encoded
+	}
+	return encoded
+}
+
+func shuffleAlphabet(alphabet string, salt string) string {
+	shuffled := []byte(alphabet)
+	if len(salt) == 0 {
+		return alphabet
+	}
+	for i, v, p := len(shuffled)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+	return string(shuffled)
+}
+
+func hashidPath(id int, length int) string {
+	alphabet := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(hashidSeparators, r) {
+			return -1
+		}
+		return r
+	}, base62Alphabet)
+	alphabet = shuffleAlphabet(alphabet, envHashidSalt)
+	lottery := alphabet[id%len(alphabet)]
+	alphabet = shuffleAlphabet(alphabet, (string(lottery) + envHashidSalt + alphabet)[:len(alphabet)])
+	hashid := string(lottery) + encodeAlphabet(id, alphabet)
+	for i := 0; len(hashid) < length; i++ {
+		if i == 0 {
+			hashid = hashid + string(hashidSeparators[id%len(hashidSeparators)])
+			continue
+		}
+		alphabet = shuffleAlphabet(alphabet, alphabet)
+		hashid = hashid + alphabet[:min(len(alphabet), length-len(hashid))]
+	}
+	return hashid
+}
+
+func wordsPath(id int, length int) string {
+	words := make([]string, length)
+	for i := range words {
+		words[i] = pathWords[randomIndex(len(pathWords))]
+	}
+	return strings.Join(words, "-")
+}
+
+func isProfane(path string) bool {
+	normalizedPath := leetReplacer.Replace(strings.ToLower(path))
+	for _, profanityWord := range profanityWords {
+		if strings.Contains(normalizedPath, profanityWord) {
+			return true
+		}
+	}
+	return false
+}
+
+func generateRedirectPath(ctx context.Context, store RedirectStore, pathStrategy string, pathLength int) (Redirect, error) {
+	strategy := pathStrategies[pathStrategy]
+	var redirect Redirect
+	for attempt := 0; attempt < generateAttempts; attempt++ {
+		if strategy.usesId {
+			nextId, db_err := store.NextRedirectId(ctx)
+			if db_err != nil {
+				return redirect, db_err
+			}
+			redirect.Id = max(nextId, redirect.Id+1)
+		}
+		redirect.Path = strategy.generate(redirect.Id, pathLength)
+		if !isProfane(redirect.Path) && !isPathReserved(redirect.Path) {
+			return redirect, nil
+		}
+	}
+	return redirect, errConflict
+}
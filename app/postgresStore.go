@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const migrationLockId = 7420151182
+const uniqueViolationCode = "23505"
+
+var postgresSortColumns = map[string]string{sortById: "id", sortByPath: "path", sortByUpdatedAt: "updated_at", sortByCreatedAt: "created_at"}
+var postgresSortTypes = map[string]string{"path": "TEXT", "updated_at": "TIMESTAMP", "created_at": "TIMESTAMP"}
+
+var postgresSearchScore = "GREATEST(CASE WHEN $2 THEN " + postgresFieldScore("lower(path)") + " ELSE 0 END, CASE WHEN $3 THEN " + postgresFieldScore("lower(url)") + " ELSE 0 END, CASE WHEN $4 THEN " + postgresFieldScore("lower(title)") + " ELSE 0 END, CASE WHEN $5 THEN GREATEST(($1=ANY(tags))::INT, word_similarity($1, array_to_string(tags, ' '))) ELSE 0 END)"
+var postgresSearchFilter = "($7::BOOLEAN IS NULL OR inactive=$7) AND tags @> COALESCE($8::TEXT[], '{}') AND ($9=0 OR status_code=$9) AND ($10::TIMESTAMPTZ IS NULL OR created_at >= $10) AND ($11::TIMESTAMPTZ IS NULL OR created_at <= $11) AND ($12::TIMESTAMPTZ IS NULL OR updated_at >= $12) AND ($13::TIMESTAMPTZ IS NULL OR updated_at <= $13) AND ($1='' OR ($2 AND " + postgresFieldMatch("lower(path)") + ") OR ($3 AND " + postgresFieldMatch("lower(url)") + ") OR ($4 AND " + postgresFieldMatch("lower(title)") + ") OR ($5 AND ($1=ANY(tags) OR $1 <% array_to_string(tags, ' '))))"
+
+const postgresMigrationsSchema = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INT PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);`
+
+const postgresRedirectFilter = "($1::BOOLEAN IS NULL OR inactive=$1) AND ($2='' OR $2=ANY(tags)) AND updated_at >= $3::TIMESTAMPTZ AND ($4='' OR path ILIKE '%' || $4 || '%') AND ($5='' OR split_part(COALESCE(canonical_url, ''), '/', 1)=$5 OR right(split_part(COALESCE(canonical_url, ''), '/', 1), length($5)+1)='.' || $5)"
+const redirectColumns = "id, path, url, updated_at::TEXT, inactive, COALESCE(canonical_url, ''), tags, status_code, created_at::TEXT, imported_clicks, title, folder, COALESCE(expires_at::TEXT, '')"
+const apiKeyColumns = "id, key_id, name, scopes, created_at::TEXT, expires_at::TEXT, last_used_at::TEXT, revoked_at::TEXT, COALESCE(rate_limit, 0), COALESCE(daily_quota, 0), COALESCE(monthly_quota, 0)"
+const auditColumns = "id, log_timestamp::TEXT, actor, source_ip, request_id, method, action, redirect_id, redirect_path, before_state, after_state, status, result"
+
+type postgresQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type PostgresStore struct {
+	pool *pgxpool.Pool
+	db   postgresQuerier
+}
+
+func newPostgresStore(databaseUrl string) (*PostgresStore, error) {
+	dbpool, db_err := pgxpool.New(context.Background(), databaseUrl)
+	if db_err != nil {
+		return nil, db_err
+	}
+	return &PostgresStore{pool: dbpool, db: dbpool}, nil
+}
+
+func (store *PostgresStore) Close() {
+	store.pool.Close()
+}
+
+func (store *PostgresStore) RunInTransaction(ctx context.Context, run func(RedirectStore) error) error {
+	tx, db_err := store.pool.Begin(ctx)
+	if db_err != nil {
+		return db_err
+	}
+	defer tx.Rollback(ctx)
+	if err := run(&PostgresStore{pool: store.pool, db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func postgresError(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return errNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode:
+		return errConflict
+	}
+	return err
+}
+
+func scanPostgresRedirect(row pgx.Row, extra ...any) (Redirect, error) {
+	var responseData Redirect
+	rowErr := row.Scan(append([]any{&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive, &responseData.CanonicalUrl, &responseData.Tags, &responseData.StatusCode, &responseData.CreatedAt, &responseData.ImportedClicks, &responseData.Title, &responseData.Folder, &responseData.ExpiresAt}, extra...)...)
+	return responseData, postgresError(rowErr)
+}
+
+func collectPostgresRedirects(rows pgx.Rows, db_err error) ([]Redirect, error) {
+	if db_err != nil {
+		return nil, db_err
+	}
+	defer rows.Close()
+	var responseData []Redirect
+	for rows.Next() {
+		temp, rowErr := scanPostgresRedirect(rows)
+		if rowErr == nil {
+			responseData = append(responseData, temp)
+		}
+	}
+	return responseData, rows.Err()
+}
+
+func (store *PostgresStore) GetRedirectUsingPath(ctx context.Context, path string) (Redirect, error) {
+	return scanPostgresRedirect(store.db.QueryRow(ctx, "SELECT "+redirectColumns+" FROM UrlRedirects WHERE path=$1 LIMIT $2", path, dbLimit))
+}
+
+func (store *PostgresStore) GetRedirectUsingId(ctx context.Context, id int) (Redirect, error) {
+	return scanPostgresRedirect(store.db.QueryRow(ctx, "SELECT "+redirectColumns+" FROM UrlRedirects WHERE id=$1 LIMIT $2", id, dbLimit))
+}
+
+func (store *PostgresStore) LockRedirect(ctx context.Context, id int) (Redirect, error) {
+	return scanPostgresRedirect(store.db.QueryRow(ctx, "SELECT "+redirectColumns+" FROM UrlRedirects WHERE id=$1 FOR UPDATE", id))
+}
+
+func (store *PostgresStore) GetRedirectUsingCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error) {
+	return scanPostgresRedirect(store.db.QueryRow(ctx, "SELECT "+redirectColumns+" FROM UrlRedirects WHERE canonical_url=$1 ORDER BY inactive, id LIMIT $2", canonicalUrl, dbLimit))
+}
+
+func (store *PostgresStore) LockCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error) {
+	if _, db_err := store.db.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", canonicalUrl); db_err != nil {
+		return Redirect{}, db_err
+	}
+	return store.GetRedirectUsingCanonicalUrl(ctx, canonicalUrl)
+}
+
+func (store *PostgresStore) NextRedirectId(ctx context.Context) (int, error) {
+	var nextId int
+	db_err := store.db.QueryRow(ctx, "SELECT nextval(pg_get_serial_sequence('urlredirects', 'id'))").Scan(&nextId)
+	return nextId, db_err
+}
+
+func (store *PostgresStore) InsertRedirect(ctx context.Context, redirect Redirect, createdBy string) (Redirect, error) {
+	responseData, db_err := scanPostgresRedirect(store.db.QueryRow(ctx, "INSERT INTO UrlRedirects (id, path, url, canonical_url, tags, status_code, updated_at, created_at, imported_clicks, title, folder, expires_at, created_by) VALUES (COALESCE(NULLIF($1,0), nextval(pg_get_serial_sequence('urlredirects', 'id'))),$2,$3,$4,COALESCE($5::TEXT[], '{}'),COALESCE(NULLIF($6,0), 302),now(),COALESCE(NULLIF($7,'')::TIMESTAMP, now()),$8,$9,$10,NULLIF($11,'')::TIMESTAMP,NULLIF($12,'')) ON CONFLICT DO NOTHING RETURNING "+redirectColumns, redirect.Id, redirect.Path, redirect.Url, redirect.CanonicalUrl, redirect.Tags, redirect.StatusCode, redirect.CreatedAt, redirect.ImportedClicks, redirect.Title, redirect.Folder, redirect.ExpiresAt, createdBy))
+	if errors.Is(db_err, errNotFound) {
+		return responseData, errConflict
+	}
+	return responseData, db_err
+}
+
+func (store *PostgresStore) UpdateRedirect(ctx context.Context, redirect Redirect) (Redirect, error) {
+	return scanPostgresRedirect(store.db.QueryRow(ctx, "UPDATE UrlRedirects SET path=$1, url=$2, canonical_url=$3, tags=COALESCE($4::TEXT[], '{}'), status_code=COALESCE(NULLIF($5,0), status_code), title=$6, folder=$7, expires_at=NULLIF($8,'')::TIMESTAMP, updated_at=now(), inactive=$9 WHERE id=$10 RETURNING "+redirectColumns, redirect.Path, redirect.Url, redirect.CanonicalUrl, redirect.Tags, redirect.StatusCode, redirect.Title, redirect.Folder, redirect.ExpiresAt, redirect.Inactive, redirect.Id))
+}
+
+func postgresFilterArgs(filter RedirectFilter) []any {
+	return []any{filter.Inactive, filter.Tag, filter.UpdatedSince, filter.Search, filter.Domain}
+}
+
+func (store *PostgresStore) ListRedirects(ctx context.Context, query RedirectQuery) ([]Redirect, error) {
+	sortColumn, comparison, direction := postgresSortColumns[query.Sort], ">", "ASC"
+	if len(sortColumn) == 0 {
+		sortColumn = "id"
+	}
+	if query.Descending {
+		comparison, direction = "<", "DESC"
+	}
+	queryArgs := append(postgresFilterArgs(query.RedirectFilter), query.Limit)
+	keyset := ""
+	switch {
+	case query.AfterId > 0 && sortColumn == "id":
+		keyset, queryArgs = " AND id "+comparison+" $7", append(queryArgs, query.AfterId)
+	case query.AfterId > 0:
+		keyset, queryArgs = " AND ("+sortColumn+", id) "+comparison+" ($7::TEXT::"+postgresSortTypes[sortColumn]+", $8)", append(queryArgs, query.AfterValue, query.AfterId)
+	}
+	return collectPostgresRedirects(store.db.Query(ctx, "SELECT "+redirectColumns+" FROM UrlRedirects WHERE "+postgresRedirectFilter+keyset+" ORDER BY "+sortColumn+" "+direction+", id "+direction+" LIMIT $6", queryArgs...))
+}
+
+func (store *PostgresStore) CountRedirects(ctx context.Context, filter RedirectFilter) (int, error) {
+	var redirectCount int
+	db_err := store.db.QueryRow(ctx, "SELECT count(*) FROM UrlRedirects WHERE "+postgresRedirectFilter, postgresFilterArgs(filter)...).Scan(&redirectCount)
+	return redirectCount, db_err
+}
+
+func postgresFieldScore(column string) string {
+	return "GREATEST(($1<>'' AND " + column + " LIKE $6)::INT, similarity(" + column + ", $1), word_similarity($1, " + column + "))"
+}
+
+func postgresFieldMatch(column string) string {
+	return "(" + column + " LIKE $6 OR " + column + " % $1 OR $1 <% " + column + ")"
+}
+
+func postgresOptionalTimestamp(timestamp time.Time) any {
+	if timestamp.IsZero() {
+		return nil
+	}
+	return timestamp
+}
+
+func (store *PostgresStore) SearchRedirects(ctx context.Context, query SearchQuery) ([]SearchResult, int, error) {
+	likePattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query.Text) + "%"
+	rows, db_err := store.db.Query(ctx, "SELECT "+redirectColumns+", score::FLOAT8, count(*) OVER() FROM (SELECT *, "+postgresSearchScore+" AS score FROM UrlRedirects WHERE "+postgresSearchFilter+") ranked ORDER BY score DESC, id LIMIT $14 OFFSET $15",
+		query.Text, slices.Contains(query.Fields, searchPathField), slices.Contains(query.Fields, searchUrlField), slices.Contains(query.Fields, searchTitleField), slices.Contains(query.Fields, searchTagsField), likePattern,
+		query.Inactive, query.Tags, query.StatusCode, postgresOptionalTimestamp(query.CreatedAfter), postgresOptionalTimestamp(query.CreatedBefore), postgresOptionalTimestamp(query.UpdatedAfter), postgresOptionalTimestamp(query.UpdatedBefore), query.Limit, query.Offset)
+	if db_err != nil {
+		return nil, 0, db_err
+	}
+	defer rows.Close()
+	var responseData []SearchResult
+	totalCount := 0
+	for rows.Next() {
+		var temp SearchResult
+		var rowErr error
+		temp.Redirect, rowErr = scanPostgresRedirect(rows, &temp.Score, &totalCount)
+		if rowErr == nil {
+			responseData = append(responseData, temp)
+		}
+	}
+	return responseData, totalCount, rows.Err()
+}
+
+func (store *PostgresStore) CountRedirectsCreatedSince(ctx context.Context, createdBy string, since time.Time) (int, error) {
+	var createdCount int
+	db_err := store.db.QueryRow(ctx, "SELECT count(*) FROM UrlRedirects WHERE created_by=$1 AND created_at >= $2::TIMESTAMPTZ", createdBy, since).Scan(&createdCount)
+	return createdCount, db_err
+}
+
+func (store *PostgresStore) BackfillCanonicalUrls(ctx context.Context, canonicalize func(string) string) (int, error) {
+	rows, db_err := store.db.Query(ctx, "SELECT id, url FROM UrlRedirects WHERE canonical_url IS NULL")
+	if db_err != nil {
+		return 0, db_err
+	}
+	var redirects []Redirect
+	for rows.Next() {
+		var temp Redirect
+		if rowErr := rows.Scan(&temp.Id, &temp.Url); rowErr == nil {
+			redirects = append(redirects, temp)
+		}
+	}
+	rows.Close()
+	if db_err := rows.Err(); db_err != nil {
+		return 0, db_err
+	}
+	for _, redirect := range redirects {
+		if _, db_err := store.db.Exec(ctx, "UPDATE UrlRedirects SET canonical_url=$1 WHERE id=$2", canonicalize(redirect.Url), redirect.Id); db_err != nil {
+			return 0, db_err
+		}
+	}
+	return len(redirects), nil
+}
+
+func (store *PostgresStore) InsertAnalyticsLog(ctx context.Context, logEntry AnalyticsLog) error {
+	_, db_err := store.db.Exec(ctx, `INSERT INTO UrlRedirects_Analytics (path, log_timestamp, status, processing_time, additional_headers, client_ip) VALUES ($1,now(),$2,$3,$4,$5)`, logEntry.Path, logEntry.Status, logEntry.ProcessingTime, logEntry.AdditionalHeaders, logEntry.ClientIp)
+	return db_err
+}
+
+func (store *PostgresStore) GetStats(ctx context.Context, start time.Time, end time.Time) (LogStatsData, error) {
+	statsData := LogStatsData{}
+	queryResults, queryErr := store.db.Query(ctx,
+		`SELECT 'path' AS col, path AS stat_key, count(id) AS stat_count FROM urlredirects_analytics WHERE log_timestamp BETWEEN TO_TIMESTAMP($1) AND TO_TIMESTAMP($2) GROUP BY path UNION ALL
+		 SELECT 'status' AS col, CAST(status AS VARCHAR) AS stat_key, count(id) AS stat_count FROM urlredirects_analytics WHERE log_timestamp BETWEEN TO_TIMESTAMP($1) AND TO_TIMESTAMP($2) GROUP BY status UNION ALL
+		 SELECT 'time' AS col, CAST(status AS VARCHAR) AS stat_key, CAST(avg(processing_time) AS INTEGER) AS stat_count FROM urlredirects_analytics WHERE log_timestamp BETWEEN TO_TIMESTAMP($1) AND TO_TIMESTAMP($2) GROUP BY status;`,
+		start.Unix(), end.Unix())
+	if queryErr != nil {
+		return statsData, queryErr
+	}
+	defer queryResults.Close()
+	for queryResults.Next() {
+		var dataItem LogQueryData
+		var dataKey string
+		var statKey pgtype.Text
+		rowErr := queryResults.Scan(&dataKey, &statKey, &dataItem.StatCount)
+		if rowErr != nil {
+			continue
+		}
+		statsData.add(dataKey, statKey.String, dataItem.StatCount)
+	}
+	return statsData, queryResults.Err()
+}
+
+func scanPostgresApiKey(row pgx.Row) (ApiKey, error) {
+	var responseData ApiKey
+	var expiresAt, lastUsedAt, revokedAt pgtype.Text
+	rowErr := row.Scan(&responseData.Id, &responseData.KeyId, &responseData.Name, &responseData.Scopes, &responseData.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt, &responseData.RateLimit, &responseData.DailyQuota, &responseData.MonthlyQuota)
+	responseData.ExpiresAt = expiresAt.String
+	responseData.LastUsedAt = lastUsedAt.String
+	responseData.Revoked = revokedAt.Valid
+	return responseData, postgresError(rowErr)
+}
+
+func (store *PostgresStore) GetApiKeyCaller(ctx context.Context, keyId string) (ApiCaller, string, error) {
+	var secretHash string
+	caller := ApiCaller{KeyId: keyId}
+	db_err := store.db.QueryRow(ctx, "SELECT secret_hash, scopes, COALESCE(rate_limit, 0), COALESCE(daily_quota, 0), COALESCE(monthly_quota, 0) FROM UrlRedirects_ApiKeys WHERE key_id=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now()) LIMIT $2", keyId, dbLimit).Scan(&secretHash, &caller.Scopes, &caller.RateLimit, &caller.DailyQuota, &caller.MonthlyQuota)
+	return caller, secretHash, postgresError(db_err)
+}
+
+func (store *PostgresStore) TouchApiKey(ctx context.Context, keyId string) error {
+	_, db_err := store.db.Exec(ctx, "UPDATE UrlRedirects_ApiKeys SET last_used_at=now() WHERE key_id=$1", keyId)
+	return db_err
+}
+
+func (store *PostgresStore) InsertApiKey(ctx context.Context, keyId string, secretHash string, keyData ApiKeyData) (ApiKey, error) {
+	return scanPostgresApiKey(store.db.QueryRow(ctx, "INSERT INTO UrlRedirects_ApiKeys (key_id, name, secret_hash, scopes, expires_at, rate_limit, daily_quota, monthly_quota) VALUES ($1,$2,$3,$4,$5,NULLIF($6,0),NULLIF($7,0),NULLIF($8,0)) RETURNING "+apiKeyColumns, keyId, keyData.Name, secretHash, keyData.Scopes, timeOrNil(keyData.ExpiresAt), keyData.RateLimit, keyData.DailyQuota, keyData.MonthlyQuota))
+}
+
+func (store *PostgresStore) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	responseData := []ApiKey{}
+	rows, db_err := store.db.Query(ctx, "SELECT "+apiKeyColumns+" FROM UrlRedirects_ApiKeys ORDER BY id")
+	if db_err != nil {
+		return responseData, db_err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		temp, rowErr := scanPostgresApiKey(rows)
+		if rowErr == nil {
+			responseData = append(responseData, temp)
+		}
+	}
+	return responseData, rows.Err()
+}
+
+func (store *PostgresStore) RevokeApiKey(ctx context.Context, keyId string) (ApiKey, error) {
+	return scanPostgresApiKey(store.db.QueryRow(ctx, "UPDATE UrlRedirects_ApiKeys SET revoked_at=COALESCE(revoked_at, now()) WHERE key_id=$1 RETURNING "+apiKeyColumns, keyId))
+}
+
+func (store *PostgresStore) InsertAuditLog(ctx context.Context, auditLog AuditLog) error {
+	_, db_err := store.db.Exec(ctx, "INSERT INTO UrlRedirects_Audit (log_timestamp, actor, source_ip, request_id, method, action, redirect_id, redirect_path, before_state, after_state, status, result) VALUES (now(),$1,$2,$3,$4,$5,NULLIF($6,0),NULLIF($7,''),$8,$9,$10,$11)",
+		auditLog.Actor, auditLog.SourceIp, auditLog.RequestId, auditLog.Method, auditLog.Action, auditLog.RedirectId, auditLog.RedirectPath, []byte(auditLog.Before), []byte(auditLog.After), auditLog.Status, auditLog.Result)
+	return db_err
+}
+
+func (store *PostgresStore) ListAuditLogs(ctx context.Context, auditQuery AuditQuery, limit int, offset int) ([]AuditLog, error) {
+	responseData := []AuditLog{}
+	rows, db_err := store.db.Query(ctx, "SELECT "+auditColumns+` FROM UrlRedirects_Audit
+		WHERE log_timestamp BETWEEN TO_TIMESTAMP($1) AND TO_TIMESTAMP($2) AND ($3 = '' OR redirect_path = $3) AND ($4 = '' OR actor = $4) ORDER BY id DESC LIMIT $5 OFFSET $6`,
+		auditQuery.Start, auditQuery.End, auditQuery.Path, auditQuery.Actor, limit, offset)
+	if db_err != nil {
+		return responseData, db_err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var temp AuditLog
+		var sourceIp, requestId, redirectPath pgtype.Text
+		var redirectId pgtype.Int4
+		rowErr := rows.Scan(&temp.Id, &temp.Timestamp, &temp.Actor, &sourceIp, &requestId, &temp.Method, &temp.Action, &redirectId, &redirectPath, &temp.Before, &temp.After, &temp.Status, &temp.Result)
+		if rowErr == nil {
+			temp.SourceIp, temp.RequestId, temp.RedirectPath = sourceIp.String, requestId.String, redirectPath.String
+			temp.RedirectId = int(redirectId.Int32)
+			responseData = append(responseData, temp)
+		}
+	}
+	return responseData, rows.Err()
+}
+
+func (store *PostgresStore) migrationDialect() string {
+	return postgresBackend
+}
+
+func (store *PostgresStore) lockMigrations(ctx context.Context) (func(), error) {
+	conn, err := store.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockId); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return func() {
+		conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", migrationLockId)
+		conn.Release()
+	}, nil
+}
+
+func (store *PostgresStore) appliedMigrationVersions(ctx context.Context) ([]int, error) {
+	if _, err := store.db.Exec(ctx, postgresMigrationsSchema); err != nil {
+		return nil, err
+	}
+	rows, err := store.db.Query(ctx, "SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+func (store *PostgresStore) applyMigration(ctx context.Context, migration Migration, up bool) error {
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if up {
+		_, err = tx.Exec(ctx, migration.Up)
+		if err == nil {
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1,$2)", migration.Version, migration.Name)
+		}
+	} else {
+		_, err = tx.Exec(ctx, migration.Down)
+		if err == nil {
+			_, err = tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version=$1", migration.Version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
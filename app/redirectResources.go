@@ -0,0 +1,361 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+const redirectResourcesEndpoint = apiBasePath + "/v2/redirects/"
+
+var errPreconditionFailed = errors.New("precondition failed")
+
+var readOnlyRedirectFields = []string{"id", "canonicalUrl", "lastUpdated", "createdAt", "importedClicks", "etag"}
+var editableRedirectFields = []string{"path", "url", "tags", "statusCode", "title", "folder", "expiresAt", "inactive"}
+
+type RedirectResource struct {
+	Redirect
+	ETag string `json:"etag"`
+}
+
+type RedirectInput struct {
+	Path       string   `json:"path,omitempty"`
+	Url        string   `json:"url,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	StatusCode int      `json:"statusCode,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Folder     string   `json:"folder,omitempty"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	Inactive   bool     `json:"inactive,omitempty"`
+}
+
+func redirectETag(redirect Redirect) string {
+	redirectHash := sha256.Sum256(toJson(redirect))
+	return `"` + hex.EncodeToString(redirectHash[:8]) + `"`
+}
+
+func matchesETag(header string, redirect Redirect) bool {
+	etag := redirectETag(redirect)
+	for _, headerTag := range strings.Split(header, ",") {
+		if headerTag = strings.TrimSpace(headerTag); headerTag == "*" || headerTag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func redirectResources(redirects []Redirect) []RedirectResource {
+	resources := []RedirectResource{}
+	for _, redirect := range redirects {
+		resources = append(resources, RedirectResource{Redirect: redirect, ETag: redirectETag(redirect)})
+	}
+	return resources
+}
+
+func validateRedirectFields(redirect Redirect, input RedirectInput, fields []string) (Redirect, error) {
+	for _, field := range editableRedirectFields {
+		if !slices.Contains(fields, field) {
+			continue
+		}
+		var fieldErr error
+		switch field {
+		case "path":
+			redirect.Path, fieldErr = validateRedirectPath(input.Path)
+		case "url":
+			validUrl, isUrlValid := validateAndFormatURL(input.Url)
+			canonicalUrl, isCanonicalUrlValid := canonicalizeUrl(input.Url)
+			if !isUrlValid || !isCanonicalUrlValid {
+				fieldErr = &FieldError{Field: "url", Message: "must be an absolute URL"}
+			}
+			redirect.Url, redirect.CanonicalUrl = validUrl, canonicalUrl
+		case "tags":
+			redirect.Tags = normalizeTags(input.Tags)
+		case "statusCode":
+			if !isRedirectStatusValid(input.StatusCode) {
+				fieldErr = &FieldError{Field: "statusCode", Message: "must be 301, 302, 303, 307 or 308"}
+			}
+			redirect.StatusCode = redirectStatus(input.StatusCode, 0)
+		case "title":
+			if !isTitleValid(input.Title) {
+				fieldErr = &FieldError{Field: "title", Message: "must be at most " + strconv.Itoa(maxTitleLength) + " characters"}
+			}
+			redirect.Title = mergeTitle(input.Title, "")
+		case "folder":
+			folder, isFolderValid := normalizeFolder(input.Folder)
+			if !isFolderValid {
+				fieldErr = &FieldError{Field: "folder", Message: "must be at most " + strconv.Itoa(maxFolderLength) + " characters without empty segments"}
+			}
+			redirect.Folder = folder
+		case "expiresAt":
+			redirect.ExpiresAt = ""
+			if len(input.ExpiresAt) > 0 {
+				expiresAt, parseErr := time.Parse(time.DateTime, input.ExpiresAt)
+				if parseErr != nil {
+					expiresAt, parseErr = time.Parse(time.RFC3339, input.ExpiresAt)
+				}
+				if parseErr != nil {
+					fieldErr = &FieldError{Field: "expiresAt", Message: "must be an RFC 3339 or YYYY-MM-DD hh:mm:ss timestamp"}
+				}
+				redirect.ExpiresAt = expiresAt.UTC().Format(time.DateTime)
+			}
+		case "inactive":
+			redirect.Inactive = input.Inactive
+		}
+		if fieldErr != nil {
+			return Redirect{}, fieldErr
+		}
+	}
+	return redirect, nil
+}
+
+func validateRedirectInput(input RedirectInput) (Redirect, error) {
+	return validateRedirectFields(Redirect{}, input, editableRedirectFields)
+}
+
+func mergeRedirectPatch(redirect Redirect, patch map[string]any) (Redirect, error) {
+	patchedFields := make([]string, 0, len(patch))
+	for field := range patch {
+		switch {
+		case slices.Contains(readOnlyRedirectFields, field):
+			return Redirect{}, &FieldError{Field: field, Message: "is read-only"}
+		case !slices.Contains(editableRedirectFields, field):
+			return Redirect{}, &FieldError{Field: field, Message: "is not a known field"}
+		}
+		patchedFields = append(patchedFields, field)
+	}
+	var input RedirectInput
+	var typeErr *json.UnmarshalTypeError
+	if decodeErr := json.Unmarshal(toJson(patch), &input); errors.As(decodeErr, &typeErr) {
+		return Redirect{}, &FieldError{Field: typeErr.Field, Message: "has an invalid type"}
+	}
+	return validateRedirectFields(redirect, input, patchedFields)
+}
+
+func parseRedirectId(w http.ResponseWriter, r *http.Request) (int, bool) {
+	redirectId, idErr := strconv.Atoi(chi.URLParam(r, "id"))
+	if idErr != nil || redirectId < 1 {
+		writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+		return 0, false
+	}
+	return redirectId, true
+}
+
+func writeRedirectResource(w http.ResponseWriter, r *http.Request, before Redirect, after Redirect, statusCode int) {
+	if isDryRun(r) {
+		writeRedirectMutation(w, r, before, after, statusCode)
+		return
+	}
+	var auditBefore any
+	if before.Id > 0 {
+		auditBefore = before
+	}
+	auditState(r, auditBefore, after)
+	etag := redirectETag(after)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(toJson(RedirectResource{Redirect: after, ETag: etag}))
+}
+
+func writeRedirectResourceError(w http.ResponseWriter, r *http.Request, caller string, err error) {
+	var fieldErr *FieldError
+	var quotaErr *QuotaError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeFieldError(w, r, fieldErr)
+	case errors.As(err, &quotaErr):
+		writeQuotaError(w, r, quotaErr)
+	case errors.Is(err, errNotFound):
+		writeApiError(w, r, http.StatusNotFound, notFoundCode, notExistMessage)
+	case errors.Is(err, errPreconditionFailed):
+		writeApiError(w, r, http.StatusPreconditionFailed, preconditionFailedCode, preconditionFailedMessage)
+	case errors.Is(err, errConflict):
+		writeApiError(w, r, http.StatusConflict, alreadyExistsCode, alreadyExistMessage)
+	default:
+		log.Println(caller+" -> ", err.Error())
+		writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+	}
+}
+
+func updateRedirectResource(r *http.Request, store Store, redirectId int, update func(RedirectStore, Redirect) (Redirect, error)) (Redirect, Redirect, error) {
+	var before, responseData Redirect
+	db_err := store.RunInTransaction(r.Context(), func(redirectStore RedirectStore) error {
+		var lockErr error
+		if before, lockErr = redirectStore.LockRedirect(r.Context(), redirectId); lockErr != nil {
+			return lockErr
+		}
+		if ifMatch := r.Header.Get("If-Match"); len(ifMatch) > 0 && !matchesETag(ifMatch, before) {
+			return errPreconditionFailed
+		}
+		updatedRedirect, updateErr := update(redirectStore, before)
+		if updateErr != nil {
+			return updateErr
+		}
+		updatedRedirect.Id = before.Id
+		if responseData, updateErr = redirectStore.UpdateRedirect(r.Context(), updatedRedirect); updateErr != nil {
+			return updateErr
+		}
+		if isDryRun(r) {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(db_err, errDryRun) {
+		db_err = nil
+	}
+	return before, responseData, db_err
+}
+
+func listRedirectResources(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectQuery, isQueryValid := parseRedirectQuery(r)
+		if !isQueryValid {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		var responseData []Redirect
+		if path := r.URL.Query().Get("path"); len(path) > 0 {
+			validPath, isPathValid := validateAndFormatPath(path)
+			dbResponse, db_err := store.GetRedirectUsingPath(r.Context(), validPath)
+			if db_err != nil && !errors.Is(db_err, errNotFound) {
+				log.Println("listRedirectResources -> ", db_err.Error())
+				writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+				return
+			}
+			if isPathValid && db_err == nil {
+				responseData = append(responseData, dbResponse)
+			}
+			w.Header().Set("X-Total-Count", strconv.Itoa(len(responseData)))
+		} else {
+			var isLoaded bool
+			if responseData, isLoaded = loadRedirectPage(w, r, store, redirectQuery); !isLoaded {
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(toJson(redirectResources(responseData)))
+	})
+}
+
+func getRedirectResource(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectId, isIdValid := parseRedirectId(w, r)
+		if !isIdValid {
+			return
+		}
+		dbResponse, db_err := store.GetRedirectUsingId(r.Context(), redirectId)
+		if db_err != nil {
+			writeRedirectResourceError(w, r, "getRedirectResource", db_err)
+			return
+		}
+		etag := redirectETag(dbResponse)
+		w.Header().Set("ETag", etag)
+		if ifNoneMatch := r.Header.Get("If-None-Match"); len(ifNoneMatch) > 0 && matchesETag(ifNoneMatch, dbResponse) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(toJson(RedirectResource{Redirect: dbResponse, ETag: etag}))
+	})
+}
+
+func createRedirectResource(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestData RedirectInput
+		err := json.NewDecoder(r.Body).Decode(&requestData)
+		if err != nil {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		validRedirect, validationErr := validateRedirectInput(requestData)
+		switch {
+		case validationErr != nil:
+			writeFieldError(w, r, validationErr)
+			return
+		case validRedirect.Inactive:
+			writeFieldError(w, r, &FieldError{Field: "inactive", Message: "must be false on create"})
+			return
+		case isPathReserved(validRedirect.Path):
+			writeFieldError(w, r, &FieldError{Field: "path", Code: pathReservedCode, Message: reservedPathMessage})
+			return
+		}
+		allowDuplicate, _ := strconv.ParseBool(r.URL.Query().Get("allowDuplicate"))
+		var responseData Redirect
+		db_err := runRedirectMutation(r, store, func(redirectStore RedirectStore) (mutateErr error) {
+			if urlErr := lockUniqueUrl(r.Context(), redirectStore, validRedirect.CanonicalUrl, allowDuplicate); urlErr != nil {
+				return urlErr
+			}
+			responseData, mutateErr = insertRedirectWithinQuota(r.Context(), redirectStore, validRedirect, callerKeyId(r))
+			return mutateErr
+		})
+		if db_err != nil {
+			writeRedirectResourceError(w, r, "createRedirectResource", db_err)
+			return
+		}
+		if !isDryRun(r) {
+			w.Header().Set("Location", redirectResourcesEndpoint+strconv.Itoa(responseData.Id))
+		}
+		writeRedirectResource(w, r, Redirect{}, responseData, http.StatusCreated)
+	})
+}
+
+func patchRedirectResource(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectId, isIdValid := parseRedirectId(w, r)
+		if !isIdValid {
+			return
+		}
+		var requestData map[string]any
+		err := json.NewDecoder(r.Body).Decode(&requestData)
+		if err != nil || requestData == nil {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		allowDuplicate, _ := strconv.ParseBool(r.URL.Query().Get("allowDuplicate"))
+		before, responseData, db_err := updateRedirectResource(r, store, redirectId, func(redirectStore RedirectStore, redirect Redirect) (Redirect, error) {
+			patchedRedirect, patchErr := mergeRedirectPatch(redirect, requestData)
+			if patchErr == nil && patchedRedirect.Path != redirect.Path && isPathReserved(patchedRedirect.Path) {
+				patchErr = &FieldError{Field: "path", Code: pathReservedCode, Message: reservedPathMessage}
+			}
+			if patchErr == nil && patchedRedirect.CanonicalUrl != redirect.CanonicalUrl {
+				patchErr = lockUniqueUrl(r.Context(), redirectStore, patchedRedirect.CanonicalUrl, allowDuplicate)
+			}
+			return patchedRedirect, patchErr
+		})
+		if db_err != nil {
+			writeRedirectResourceError(w, r, "patchRedirectResource", db_err)
+			return
+		}
+		writeRedirectResource(w, r, before, responseData, http.StatusOK)
+	})
+}
+
+func disableRedirectResource(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectId, isIdValid := parseRedirectId(w, r)
+		if !isIdValid {
+			return
+		}
+		before, responseData, db_err := updateRedirectResource(r, store, redirectId, func(redirectStore RedirectStore, redirect Redirect) (Redirect, error) {
+			redirect.Inactive = true
+			return redirect, nil
+		})
+		if db_err != nil {
+			writeRedirectResourceError(w, r, "disableRedirectResource", db_err)
+			return
+		}
+		writeRedirectResource(w, r, before, responseData, http.StatusOK)
+	})
+}
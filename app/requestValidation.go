@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func schemaFieldName(field string, name string) string {
+	if len(field) == 0 {
+		return name
+	}
+	return field + "." + name
+}
+
+func (document *OpenApiDocument) validateValue(schema *OpenApiSchema, value any, field string) error {
+	schema = document.resolveSchema(schema)
+	if schema == nil || schema.ReadOnly {
+		return nil
+	}
+	if value == nil {
+		if schema.Nullable || (len(schema.Type) == 0 && len(schema.AllOf) == 0) {
+			return nil
+		}
+		return &FieldError{Field: field, Message: "must not be null"}
+	}
+	for _, allOfSchema := range schema.AllOf {
+		if validationErr := document.validateValue(allOfSchema, value, field); validationErr != nil {
+			return validationErr
+		}
+	}
+	object, isObject := value.(map[string]any)
+	for _, requiredField := range schema.Required {
+		if _, isPresent := object[requiredField]; isObject && !isPresent {
+			return &FieldError{Field: schemaFieldName(field, requiredField), Message: "is required"}
+		}
+	}
+	switch schema.Type {
+	case "object":
+		if !isObject {
+			return &FieldError{Field: field, Message: "must be an object"}
+		}
+		objectFields := make([]string, 0, len(object))
+		for objectField := range object {
+			objectFields = append(objectFields, objectField)
+		}
+		slices.Sort(objectFields)
+		for _, objectField := range objectFields {
+			propertySchema, isKnown := schema.Properties[objectField]
+			if !isKnown {
+				additionalSchema, isSchema := schema.AdditionalProperties.(*OpenApiSchema)
+				if !isSchema {
+					return &FieldError{Field: schemaFieldName(field, objectField), Message: "is not a known field"}
+				}
+				propertySchema = additionalSchema
+			}
+			if validationErr := document.validateValue(propertySchema, object[objectField], schemaFieldName(field, objectField)); validationErr != nil {
+				return validationErr
+			}
+		}
+	case "array":
+		items, isArray := value.([]any)
+		if !isArray {
+			return &FieldError{Field: field, Message: "must be an array"}
+		}
+		for i, item := range items {
+			if validationErr := document.validateValue(schema.Items, item, field+"["+strconv.Itoa(i)+"]"); validationErr != nil {
+				return validationErr
+			}
+		}
+	case "string":
+		text, isString := value.(string)
+		if !isString {
+			return &FieldError{Field: field, Message: "must be a string"}
+		}
+		if _, parseErr := time.Parse(time.RFC3339, text); schema.Format == "date-time" && parseErr != nil {
+			return &FieldError{Field: field, Message: "must be an RFC 3339 timestamp"}
+		}
+	case "integer":
+		number, isNumber := value.(json.Number)
+		if _, intErr := number.Int64(); !isNumber || intErr != nil {
+			return &FieldError{Field: field, Message: "must be an integer"}
+		}
+	case "number":
+		if _, isNumber := value.(json.Number); !isNumber {
+			return &FieldError{Field: field, Message: "must be a number"}
+		}
+	case "boolean":
+		if _, isBool := value.(bool); !isBool {
+			return &FieldError{Field: field, Message: "must be a boolean"}
+		}
+	}
+	return nil
+}
+
+func (document *OpenApiDocument) requestSchema(router chi.Routes, r *http.Request) *OpenApiSchema {
+	routePath := r.URL.Path
+	if routeContext := chi.RouteContext(r.Context()); routeContext != nil && len(routeContext.RoutePath) > 0 {
+		routePath = routeContext.RoutePath
+	}
+	matchContext := chi.NewRouteContext()
+	if !router.Match(matchContext, r.Method, routePath) {
+		return nil
+	}
+	operation := document.operation(r.Method, matchContext.RoutePattern())
+	if operation == nil || operation.RequestBody == nil {
+		return nil
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return operation.RequestBody.Content[contentType].Schema
+}
+
+func validateRequest(router chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			schema := openApiDocument.requestSchema(router, r)
+			if schema == nil || r.ContentLength == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			requestBody, readErr := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(readErr, &maxBytesErr) {
+				writeApiError(w, r, http.StatusRequestEntityTooLarge, requestTooLargeCode, requestTooLargeMessage)
+				return
+			}
+			if readErr != nil {
+				writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+				return
+			}
+			var requestData any
+			decoder := json.NewDecoder(bytes.NewReader(requestBody))
+			decoder.UseNumber()
+			if decodeErr := decoder.Decode(&requestData); decodeErr != nil {
+				writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+				return
+			}
+			if validationErr := openApiDocument.validateValue(schema, requestData, ""); validationErr != nil {
+				writeFieldError(w, r, validationErr)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(requestBody))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
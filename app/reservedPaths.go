@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const healthCheckPath = "/app/health"
+const reservedPrefixSuffix = "/*"
+
+var envReservedPaths = os.Getenv("RESERVED_PATHS")
+
+var reservedPaths = parseReservedPaths(envReservedPaths)
+
+func normalizeReservedPath(reservedPath string) string {
+	reservedPath = strings.ToLower(strings.Trim(strings.TrimSpace(reservedPath), "/"))
+	if strings.HasSuffix(reservedPath, "*") {
+		return strings.TrimRight(reservedPath, "/*") + reservedPrefixSuffix
+	}
+	return reservedPath
+}
+
+func parseReservedPaths(envPaths string) []string {
+	paths := []string{normalizeReservedPath(healthCheckPath)}
+	for _, reservedPath := range strings.Split(envPaths, ",") {
+		if reservedPath = normalizeReservedPath(reservedPath); len(strings.TrimSuffix(reservedPath, reservedPrefixSuffix)) > 0 && !slices.Contains(paths, reservedPath) {
+			paths = append(paths, reservedPath)
+		}
+	}
+	return paths
+}
+
+func routeReservedPath(route string) string {
+	firstSegment, _, hasRest := strings.Cut(strings.Trim(route, "/"), "/")
+	if hasRest || strings.HasSuffix(route, "*") {
+		return normalizeReservedPath(firstSegment + reservedPrefixSuffix)
+	}
+	return normalizeReservedPath(firstSegment)
+}
+
+func reserveRoutePaths(router chi.Routes) {
+	chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if reservedPath := routeReservedPath(route); len(strings.TrimSuffix(reservedPath, reservedPrefixSuffix)) > 0 && !slices.Contains(reservedPaths, reservedPath) {
+			reservedPaths = append(reservedPaths, reservedPath)
+		}
+		return nil
+	})
+}
+
+func isPathReserved(path string) bool {
+	path = strings.ToLower(path)
+	for _, reservedPath := range reservedPaths {
+		prefix, isPrefix := strings.CutSuffix(reservedPath, reservedPrefixSuffix)
+		if path == prefix || (isPrefix && strings.HasPrefix(path, prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func reportReservedPathCollisions(store RedirectStore) {
+	var collisions int
+	redirects, db_err := store.ListRedirects(context.Background(), RedirectQuery{Limit: exportBatchSize})
+	for db_err == nil && len(redirects) > 0 {
+		for _, redirect := range redirects {
+			if isPathReserved(redirect.Path) {
+				collisions++
+				log.Println("Reserved Path Collision: id", redirect.Id, "path /"+redirect.Path, "collides with a reserved path")
+			}
+		}
+		if len(redirects) < exportBatchSize {
+			break
+		}
+		redirects, db_err = store.ListRedirects(context.Background(), RedirectQuery{AfterId: redirects[len(redirects)-1].Id, Limit: exportBatchSize})
+	}
+	if db_err != nil {
+		log.Println("Reserved Path Check Error:", db_err)
+		return
+	}
+	if collisions > 0 {
+		log.Println("Redirects colliding with reserved paths:", collisions)
+	}
+}
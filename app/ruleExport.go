@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const nginxTarget = "nginx"
+const apacheTarget = "apache"
+const htaccessTarget = "htaccess"
+const netlifyTarget = "netlify"
+const caddyTarget = "caddy"
+
+var unsafeRuleRegex = regexp.MustCompile(`[\s"'\\${};<>]`)
+
+type RuleTarget struct {
+	filename string
+	write    func(w io.Writer, redirects []Redirect)
+}
+
+var ruleTargets = map[string]RuleTarget{
+	nginxTarget:    {filename: "redirects.conf", write: writeNginxRules},
+	apacheTarget:   {filename: "redirects.map", write: writeApacheRules},
+	htaccessTarget: {filename: ".htaccess", write: writeHtaccessRules},
+	netlifyTarget:  {filename: "_redirects", write: writeNetlifyRules},
+	caddyTarget:    {filename: "redirects.caddy", write: writeCaddyRules},
+}
+
+func isRuleSafe(redirect Redirect) bool {
+	return len(redirect.Path) > 0 && !unsafeRuleRegex.MatchString(redirect.Path) && !unsafeRuleRegex.MatchString(redirect.Url)
+}
+
+func writeRules(w io.Writer, redirects []Redirect, writeRule func(redirect Redirect)) {
+	for _, redirect := range redirects {
+		if !isRuleSafe(redirect) {
+			fmt.Fprintf(w, "# unsupported: %q\n", "/"+redirect.Path)
+			continue
+		}
+		writeRule(redirect)
+	}
+}
+
+func writeNginxRules(w io.Writer, redirects []Redirect) {
+	fmt.Fprintln(w, "# http { include redirects.conf; }")
+	for _, statusCode := range redirectStatusCodes {
+		fmt.Fprintf(w, "# server { if ($redirect_%d) { return %d $redirect_%d; } }\n", statusCode, statusCode, statusCode)
+	}
+	for _, statusCode := range redirectStatusCodes {
+		var statusRedirects []Redirect
+		for _, redirect := range redirects {
+			if redirect.StatusCode == statusCode {
+				statusRedirects = append(statusRedirects, redirect)
+			}
+		}
+		fmt.Fprintf(w, "\nmap $uri $redirect_%d {\n    default \"\";\n", statusCode)
+		writeRules(w, statusRedirects, func(redirect Redirect) {
+			fmt.Fprintf(w, "    \"/%s\" \"%s\";\n", redirect.Path, buildUri(redirect.Url))
+		})
+		fmt.Fprintln(w, "}")
+	}
+}
+
+func writeApacheRules(w io.Writer, redirects []Redirect) {
+	fmt.Fprintln(w, "# RewriteEngine On")
+	fmt.Fprintln(w, "# RewriteMap redirects \"txt:/etc/apache2/redirects.map\"")
+	for _, statusCode := range redirectStatusCodes {
+		fmt.Fprintf(w, "# RewriteCond ${redirects:$1} ^%d:(.+)$\n# RewriteRule ^/(.+)$ %%1 [R=%d,NE,L]\n", statusCode, statusCode)
+	}
+	writeRules(w, redirects, func(redirect Redirect) {
+		fmt.Fprintf(w, "%s %d:%s\n", redirect.Path, redirect.StatusCode, buildUri(redirect.Url))
+	})
+}
+
+func writeHtaccessRules(w io.Writer, redirects []Redirect) {
+	writeRules(w, redirects, func(redirect Redirect) {
+		fmt.Fprintf(w, "RedirectMatch %d \"^/%s$\" \"%s\"\n", redirect.StatusCode, regexp.QuoteMeta(redirect.Path), buildUri(redirect.Url))
+	})
+}
+
+func writeNetlifyRules(w io.Writer, redirects []Redirect) {
+	writeRules(w, redirects, func(redirect Redirect) {
+		fmt.Fprintf(w, "/%s %s %d\n", redirect.Path, buildUri(redirect.Url), redirect.StatusCode)
+	})
+}
+
+func writeCaddyRules(w io.Writer, redirects []Redirect) {
+	fmt.Fprintln(w, "# import redirects.caddy")
+	fmt.Fprintln(w, "# example.com { import url_redirects }")
+	fmt.Fprintln(w, "(url_redirects) {")
+	writeRules(w, redirects, func(redirect Redirect) {
+		fmt.Fprintf(w, "\tredir /%s %s %d\n", redirect.Path, buildUri(redirect.Url), redirect.StatusCode)
+	})
+	fmt.Fprintln(w, "}")
+}
+
+func exportRules(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ruleTarget, isTargetValid := ruleTargets[strings.ToLower(chi.URLParam(r, "target"))]
+		if !isTargetValid {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		isInactive := false
+		var redirects []Redirect
+		for afterId := 0; ; {
+			batch, db_err := store.ListRedirects(r.Context(), RedirectQuery{RedirectFilter: RedirectFilter{Inactive: &isInactive}, AfterId: afterId, Limit: exportBatchSize})
+			if db_err != nil {
+				log.Println("exportRules -> ", db_err.Error())
+				writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+				return
+			}
+			for _, redirect := range batch {
+				if !isRedirectExpired(redirect) {
+					redirects = append(redirects, redirect)
+				}
+			}
+			if len(batch) < exportBatchSize {
+				break
+			}
+			afterId = batch[len(batch)-1].Id
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+ruleTarget.filename+"\"")
+		w.WriteHeader(http.StatusOK)
+		ruleTarget.write(w, redirects)
+	})
+}
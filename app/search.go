@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const searchPathField = "path"
+const searchUrlField = "url"
+const searchTitleField = "title"
+const searchTagsField = "tags"
+
+const searchSimilarityThreshold = 0.3
+const searchWordSimilarityThreshold = 0.6
+const searchFragmentLength = 120
+
+var searchFields = []string{searchPathField, searchUrlField, searchTitleField, searchTagsField}
+var searchWordRegex = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func searchTrigrams(text string) map[string]bool {
+	trigrams := map[string]bool{}
+	for _, word := range searchWords(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trigrams[string(padded[i:i+3])] = true
+		}
+	}
+	return trigrams
+}
+
+func commonTrigrams(x map[string]bool, y map[string]bool) int {
+	common := 0
+	for trigram := range x {
+		if y[trigram] {
+			common++
+		}
+	}
+	return common
+}
+
+func trigramSimilarity(x string, y string) float64 {
+	xTrigrams, yTrigrams := searchTrigrams(x), searchTrigrams(y)
+	common := commonTrigrams(xTrigrams, yTrigrams)
+	if union := len(xTrigrams) + len(yTrigrams) - common; union > 0 {
+		return float64(common) / float64(union)
+	}
+	return 0
+}
+
+func wordSimilarity(query string, text string) float64 {
+	queryTrigrams := searchTrigrams(query)
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+	return float64(commonTrigrams(queryTrigrams, searchTrigrams(text))) / float64(len(queryTrigrams))
+}
+
+func searchFieldValue(redirect Redirect, field string) string {
+	switch field {
+	case searchPathField:
+		return redirect.Path
+	case searchUrlField:
+		return redirect.Url
+	case searchTitleField:
+		return redirect.Title
+	}
+	return strings.Join(redirect.Tags, " ")
+}
+
+func scoreSearchField(redirect Redirect, field string, text string) (float64, bool) {
+	value := strings.ToLower(searchFieldValue(redirect, field))
+	if field == searchTagsField && slices.Contains(redirect.Tags, text) {
+		return 1, true
+	}
+	if field != searchTagsField && strings.Contains(value, text) {
+		return 1, true
+	}
+	wordScore := wordSimilarity(text, value)
+	if field == searchTagsField {
+		return wordScore, wordScore >= searchWordSimilarityThreshold
+	}
+	similarity := trigramSimilarity(value, text)
+	return max(similarity, wordScore), wordScore >= searchWordSimilarityThreshold || similarity >= searchSimilarityThreshold
+}
+
+func matchesSearchFilter(redirect Redirect, query SearchQuery) bool {
+	switch {
+	case query.Inactive != nil && redirect.Inactive != *query.Inactive:
+		return false
+	case query.StatusCode != 0 && redirect.StatusCode != query.StatusCode:
+		return false
+	case !query.CreatedAfter.IsZero() && redirect.CreatedAt < formatTimestamp(query.CreatedAfter):
+		return false
+	case !query.CreatedBefore.IsZero() && redirect.CreatedAt > formatTimestamp(query.CreatedBefore):
+		return false
+	case !query.UpdatedAfter.IsZero() && redirect.LastUpdated < formatTimestamp(query.UpdatedAfter):
+		return false
+	case !query.UpdatedBefore.IsZero() && redirect.LastUpdated > formatTimestamp(query.UpdatedBefore):
+		return false
+	}
+	for _, tag := range query.Tags {
+		if !slices.Contains(redirect.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func rankRedirects(redirects []Redirect, query SearchQuery) ([]SearchResult, int) {
+	var results []SearchResult
+	for _, redirect := range redirects {
+		if !matchesSearchFilter(redirect, query) {
+			continue
+		}
+		result, isMatch := SearchResult{Redirect: redirect}, len(query.Text) == 0
+		for _, field := range query.Fields {
+			if len(query.Text) == 0 {
+				break
+			}
+			fieldScore, isFieldMatch := scoreSearchField(redirect, field, query.Text)
+			result.Score, isMatch = max(result.Score, fieldScore), isMatch || isFieldMatch
+		}
+		if isMatch {
+			results = append(results, result)
+		}
+	}
+	slices.SortStableFunc(results, func(x, y SearchResult) int {
+		switch {
+		case x.Score > y.Score:
+			return -1
+		case x.Score < y.Score:
+			return 1
+		}
+		return x.Id - y.Id
+	})
+	totalCount := len(results)
+	results = results[min(query.Offset, len(results)):]
+	return results[:min(query.Limit, len(results))], totalCount
+}
+
+func highlightSearchField(value string, text string) string {
+	var matches [][]int
+	if len(text) > 0 {
+		matches = regexp.MustCompile("(?i)"+regexp.QuoteMeta(text)).FindAllStringIndex(value, -1)
+	}
+	if len(matches) == 0 {
+		queryWords := searchWords(text)
+		for _, wordIndex := range searchWordRegex.FindAllStringIndex(value, -1) {
+			word := value[wordIndex[0]:wordIndex[1]]
+			for _, queryWord := range queryWords {
+				if trigramSimilarity(word, queryWord) >= searchSimilarityThreshold || wordSimilarity(queryWord, word) >= searchWordSimilarityThreshold {
+					matches = append(matches, wordIndex)
+					break
+				}
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+	start, end := 0, len(value)
+	if utf8.RuneCountInString(value) > searchFragmentLength {
+		start, end = max(0, matches[0][0]-searchFragmentLength/2), min(len(value), matches[0][0]+searchFragmentLength/2)
+		for start > 0 && !utf8.RuneStart(value[start]) {
+			start--
+		}
+		for end < len(value) && !utf8.RuneStart(value[end]) {
+			end++
+		}
+	}
+	var fragment strings.Builder
+	if start > 0 {
+		fragment.WriteString("…")
+	}
+	position := start
+	for _, match := range matches {
+		if match[0] < position || match[1] > end {
+			continue
+		}
+		fragment.WriteString(html.EscapeString(value[position:match[0]]))
+		fragment.WriteString("<em>" + html.EscapeString(value[match[0]:match[1]]) + "</em>")
+		position = match[1]
+	}
+	fragment.WriteString(html.EscapeString(value[position:end]))
+	if end < len(value) {
+		fragment.WriteString("…")
+	}
+	return fragment.String()
+}
+
+func validateSearchQuery(searchQuery SearchQuery) (SearchQuery, bool) {
+	searchQuery.Text = strings.ToLower(strings.TrimSpace(searchQuery.Text))
+	if len(searchQuery.Fields) == 0 {
+		searchQuery.Fields = slices.Clone(searchFields)
+	}
+	for i, field := range searchQuery.Fields {
+		searchQuery.Fields[i] = strings.ToLower(strings.TrimSpace(field))
+		if !slices.Contains(searchFields, searchQuery.Fields[i]) {
+			return searchQuery, false
+		}
+	}
+	if searchQuery.Limit == 0 {
+		searchQuery.Limit = pageLimit
+	}
+	searchQuery.Tags = normalizeTags(searchQuery.Tags)
+	isQueryValid := searchQuery.Limit > 0 && searchQuery.Limit <= maxPageLimit && searchQuery.Offset >= 0 && isRedirectStatusValid(searchQuery.StatusCode)
+	return searchQuery, isQueryValid
+}
+
+func findRedirects(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestData SearchQuery
+		err := json.NewDecoder(r.Body).Decode(&requestData)
+		searchQuery, isQueryValid := validateSearchQuery(requestData)
+		if err != nil || !isQueryValid {
+			writeApiError(w, r, http.StatusBadRequest, badRequestCode, badRequest)
+			return
+		}
+		results, totalCount, db_err := store.SearchRedirects(r.Context(), searchQuery)
+		if db_err != nil {
+			log.Println("findRedirects -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		responseData := SearchResponse{Total: totalCount, Results: []SearchMatch{}}
+		for _, result := range results {
+			searchMatch := SearchMatch{Redirect: result.Redirect, Score: result.Score, Highlights: map[string]string{}}
+			for _, field := range searchQuery.Fields {
+				if highlight := highlightSearchField(searchFieldValue(result.Redirect, field), searchQuery.Text); len(highlight) > 0 {
+					searchMatch.Highlights[field] = highlight
+				}
+			}
+			responseData.Results = append(responseData.Results, searchMatch)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(toJson(responseData))
+	})
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const sqliteMigrationsSchema = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  applied_at TEXT NOT NULL
+);`
+
+const sqliteRedirectHost = "substr(COALESCE(canonical_url, ''), 1, instr(COALESCE(canonical_url, '') || '/', '/') - 1)"
+const sqliteRedirectFilter = "(? IS NULL OR inactive=?) AND (?='' OR instr(','||tags||',', ','||?||',')>0) AND updated_at>=? AND (?='' OR path LIKE '%'||?||'%') AND (?='' OR " + sqliteRedirectHost + "=? OR substr(" + sqliteRedirectHost + ", -length(?)-1)='.'||?)"
+const sqliteRedirectColumns = "id, path, url, updated_at, inactive, COALESCE(canonical_url, ''), tags, status_code, created_at, imported_clicks, title, folder, COALESCE(expires_at, '')"
+const sqliteApiKeyColumns = "id, key_id, name, scopes, created_at, expires_at, last_used_at, revoked_at, COALESCE(rate_limit, 0), COALESCE(daily_quota, 0), COALESCE(monthly_quota, 0)"
+const sqliteAuditColumns = "id, log_timestamp, actor, source_ip, request_id, method, action, redirect_id, redirect_path, before_state, after_state, status, result"
+
+var sqliteSortColumns = map[string]string{sortById: "id", sortByPath: "path", sortByUpdatedAt: "updated_at", sortByCreatedAt: "created_at"}
+
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type SqliteStore struct {
+	conn *sql.DB
+	db   sqliteQuerier
+}
+
+type sqlRow interface {
+	Scan(dest ...any) error
+}
+
+func newSqliteStore(databasePath string) (*SqliteStore, error) {
+	if len(strings.TrimSpace(databasePath)) == 0 {
+		databasePath = "url-redirect.db"
+	}
+	if !strings.Contains(databasePath, "?") {
+		databasePath = databasePath + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	}
+	db, db_err := sql.Open("sqlite", databasePath)
+	if db_err != nil {
+		return nil, db_err
+	}
+	db.SetMaxOpenConns(1)
+	if pingErr := db.Ping(); pingErr != nil {
+		db.Close()
+		return nil, pingErr
+	}
+	return &SqliteStore{conn: db, db: db}, nil
+}
+
+func (store *SqliteStore) Close() {
+	store.conn.Close()
+}
+
+func (store *SqliteStore) RunInTransaction(ctx context.Context, run func(RedirectStore) error) error {
+	tx, db_err := store.conn.BeginTx(ctx, nil)
+	if db_err != nil {
+		return db_err
+	}
+	defer tx.Rollback()
+	if err := run(&SqliteStore{conn: store.conn, db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func sqliteError(err error) error {
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errNotFound
+	case errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY):
+		return errConflict
+	}
+	return err
+}
+
+func sqliteNow() string {
+	return formatTimestamp(time.Now())
+}
+
+func scanSqliteRedirect(row sqlRow) (Redirect, error) {
+	var responseData Redirect
+	var tags string
+	rowErr := row.Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive, &responseData.CanonicalUrl, &tags, &responseData.StatusCode, &responseData.CreatedAt, &responseData.ImportedClicks, &responseData.Title, &responseData.Folder, &responseData.ExpiresAt)
+	responseData.Tags = splitList(tags)
+	return responseData, sqliteError(rowErr)
+}
+
+func collectSqliteRedirects(rows *sql.Rows, db_err error) ([]Redirect, error) {
+	if db_err != nil {
+		return nil, db_err
+	}
+	defer rows.Close()
+	var responseData []Redirect
+	for rows.Next() {
+		temp, rowErr := scanSqliteRedirect(rows)
+		if rowErr == nil {
+			responseData = append(responseData, temp)
+		}
+	}
+	return responseData, rows.Err()
+}
+
+func (store *SqliteStore) GetRedirectUsingPath(ctx context.Context, path string) (Redirect, error) {
+	return scanSqliteRedirect(store.db.QueryRowContext(ctx, "SELECT "+sqliteRedirectColumns+" FROM UrlRedirects WHERE path=? LIMIT ?", path, dbLimit))
+}
+
+func (store *SqliteStore) GetRedirectUsingId(ctx context.Context, id int) (Redirect, error) {
+	return scanSqliteRedirect(store.db.QueryRowContext(ctx, "SELECT "+sqliteRedirectColumns+" FROM UrlRedirects WHERE id=? LIMIT ?", id, dbLimit))
+}
+
+func (store *SqliteStore) LockRedirect(ctx context.Context, id int) (Redirect, error) {
+	return store.GetRedirectUsingId(ctx, id)
+}
+
+func (store *SqliteStore) GetRedirectUsingCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error) {
+	return scanSqliteRedirect(store.db.QueryRowContext(ctx, "SELECT "+sqliteRedirectColumns+" FROM UrlRedirects WHERE canonical_url=? ORDER BY inactive, id LIMIT ?", canonicalUrl, dbLimit))
+}
+
+func (store *SqliteStore) LockCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error) {
+	return store.GetRedirectUsingCanonicalUrl(ctx, canonicalUrl)
+}
+
+func (store *SqliteStore) NextRedirectId(ctx context.Context) (int, error) {
+	var nextId int
+	db_err := store.db.QueryRowContext(ctx, "SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name='UrlRedirects'), 0) + 1").Scan(&nextId)
+	return nextId, db_err
+}
+
+func (store *SqliteStore) InsertRedirect(ctx context.Context, redirect Redirect, createdBy string) (Redirect, error) {
+	now := sqliteNow()
+	responseData, db_err := scanSqliteRedirect(store.db.QueryRowContext(ctx, "INSERT INTO UrlRedirects (id, path, url, canonical_url, tags, status_code, updated_at, created_at, imported_clicks, title, folder, expires_at, created_by) VALUES (NULLIF(?,0),?,?,?,?,COALESCE(NULLIF(?,0), 302),?,COALESCE(NULLIF(?,''), ?),?,?,?,NULLIF(?,''),NULLIF(?,'')) ON CONFLICT DO NOTHING RETURNING "+sqliteRedirectColumns, redirect.Id, redirect.Path, redirect.Url, redirect.CanonicalUrl, strings.Join(redirect.Tags, ","), redirect.StatusCode, now, redirect.CreatedAt, now, redirect.ImportedClicks, redirect.Title, redirect.Folder, redirect.ExpiresAt, createdBy))
+	if errors.Is(db_err, errNotFound) {
+		return responseData, errConflict
+	}
+	return responseData, db_err
+}
+
+func (store *SqliteStore) UpdateRedirect(ctx context.Context, redirect Redirect) (Redirect, error) {
+	return scanSqliteRedirect(store.db.QueryRowContext(ctx, "UPDATE UrlRedirects SET path=?, url=?, canonical_url=?, tags=?, status_code=COALESCE(NULLIF(?,0), status_code), title=?, folder=?, expires_at=NULLIF(?,''), updated_at=?, inactive=? WHERE id=? RETURNING "+sqliteRedirectColumns, redirect.Path, redirect.Url, redirect.CanonicalUrl, strings.Join(redirect.Tags, ","), redirect.StatusCode, redirect.Title, redirect.Folder, redirect.ExpiresAt, sqliteNow(), redirect.Inactive, redirect.Id))
+}
+
+func sqliteFilterArgs(filter RedirectFilter) []any {
+	return []any{filter.Inactive, filter.Inactive, filter.Tag, filter.Tag, formatTimestamp(filter.UpdatedSince), filter.Search, filter.Search, filter.Domain, filter.Domain, filter.Domain, filter.Domain}
+}
+
+func (store *SqliteStore) ListRedirects(ctx context.Context, query RedirectQuery) ([]Redirect, error) {
+	sortColumn, comparison, direction := sqliteSortColumns[query.Sort], ">", "ASC"
+	if len(sortColumn) == 0 {
+		sortColumn = "id"
+	}
+	if query.Descending {
+		comparison, direction = "<", "DESC"
+	}
+	queryArgs := sqliteFilterArgs(query.RedirectFilter)
+	keyset := ""
+	switch {
+	case query.AfterId > 0 && sortColumn == "id":
+		keyset, queryArgs = " AND id "+comparison+" ?", append(queryArgs, query.AfterId)
+	case query.AfterId > 0:
+		keyset, queryArgs = " AND ("+sortColumn+", id) "+comparison+" (?, ?)", append(queryArgs, query.AfterValue, query.AfterId)
+	}
+	return collectSqliteRedirects(store.db.QueryContext(ctx, "SELECT "+sqliteRedirectColumns+" FROM UrlRedirects WHERE "+sqliteRedirectFilter+keyset+" ORDER BY "+sortColumn+" "+direction+", id "+direction+" LIMIT ?", append(queryArgs, query.Limit)...))
+}
+
+func (store *SqliteStore) CountRedirects(ctx context.Context, filter RedirectFilter) (int, error) {
+	var redirectCount int
+	db_err := store.db.QueryRowContext(ctx, "SELECT count(*) FROM UrlRedirects WHERE "+sqliteRedirectFilter, sqliteFilterArgs(filter)...).Scan(&redirectCount)
+	return redirectCount, db_err
+}
+
+func sqliteOptionalTimestamp(timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return ""
+	}
+	return formatTimestamp(timestamp)
+}
+
+func (store *SqliteStore) SearchRedirects(ctx context.Context, query SearchQuery) ([]SearchResult, int, error) {
+	createdAfter, createdBefore := sqliteOptionalTimestamp(query.CreatedAfter), sqliteOptionalTimestamp(query.CreatedBefore)
+	updatedAfter, updatedBefore := sqliteOptionalTimestamp(query.UpdatedAfter), sqliteOptionalTimestamp(query.UpdatedBefore)
+	redirects, db_err := collectSqliteRedirects(store.db.QueryContext(ctx, "SELECT "+sqliteRedirectColumns+" FROM UrlRedirects WHERE (? IS NULL OR inactive=?) AND (?=0 OR status_code=?) AND (?='' OR created_at>=?) AND (?='' OR created_at<=?) AND (?='' OR updated_at>=?) AND (?='' OR updated_at<=?) ORDER BY id",
+		query.Inactive, query.Inactive, query.StatusCode, query.StatusCode, createdAfter, createdAfter, createdBefore, createdBefore, updatedAfter, updatedAfter, updatedBefore, updatedBefore))
+	if db_err != nil {
+		return nil, 0, db_err
+	}
+	results, totalCount := rankRedirects(redirects, query)
+	return results, totalCount, nil
+}
+
+func (store *SqliteStore) CountRedirectsCreatedSince(ctx context.Context, createdBy string, since time.Time) (int, error) {
+	var createdCount int
+	db_err := store.db.QueryRowContext(ctx, "SELECT count(*) FROM UrlRedirects WHERE created_by=? AND created_at >= ?", createdBy, formatTimestamp(since)).Scan(&createdCount)
+	return createdCount, db_err
+}
+
+func (store *SqliteStore) BackfillCanonicalUrls(ctx context.Context, canonicalize func(string) string) (int, error) {
+	rows, db_err := store.db.QueryContext(ctx, "SELECT id, url FROM UrlRedirects WHERE canonical_url IS NULL")
+	if db_err != nil {
+		return 0, db_err
+	}
+	var redirects []Redirect
+	for rows.Next() {
+		var temp Redirect
+		if rowErr := rows.Scan(&temp.Id, &temp.Url); rowErr == nil {
+			redirects = append(redirects, temp)
+		}
+	}
+	rows.Close()
+	if db_err := rows.Err(); db_err != nil {
+		return 0, db_err
+	}
+	for _, redirect := range redirects {
+		if _, db_err := store.db.ExecContext(ctx, "UPDATE UrlRedirects SET canonical_url=? WHERE id=?", canonicalize(redirect.Url), redirect.Id); db_err != nil {
+			return 0, db_err
+		}
+	}
+	return len(redirects), nil
+}
+
+func (store *SqliteStore) InsertAnalyticsLog(ctx context.Context, logEntry AnalyticsLog) error {
+	_, db_err := store.db.ExecContext(ctx, "INSERT INTO UrlRedirects_Analytics (path, log_timestamp, status, processing_time, additional_headers, client_ip) VALUES (?,?,?,?,?,?)", logEntry.Path, sqliteNow(), logEntry.Status, logEntry.ProcessingTime, logEntry.AdditionalHeaders, logEntry.ClientIp)
+	return db_err
+}
+
+func (store *SqliteStore) GetStats(ctx context.Context, start time.Time, end time.Time) (LogStatsData, error) {
+	statsData := LogStatsData{}
+	queryResults, queryErr := store.db.QueryContext(ctx,
+		`SELECT 'path' AS col, path AS stat_key, count(id) AS stat_count FROM UrlRedirects_Analytics WHERE log_timestamp BETWEEN ?1 AND ?2 GROUP BY path UNION ALL
+		 SELECT 'status' AS col, CAST(status AS TEXT) AS stat_key, count(id) AS stat_count FROM UrlRedirects_Analytics WHERE log_timestamp BETWEEN ?1 AND ?2 GROUP BY status UNION ALL
+		 SELECT 'time' AS col, CAST(status AS TEXT) AS stat_key, CAST(avg(processing_time) AS INTEGER) AS stat_count FROM UrlRedirects_Analytics WHERE log_timestamp BETWEEN ?1 AND ?2 GROUP BY status;`,
+		formatTimestamp(start), formatTimestamp(end))
+	if queryErr != nil {
+		return statsData, queryErr
+	}
+	defer queryResults.Close()
+	for queryResults.Next() {
+		var dataKey string
+		var statKey sql.NullString
+		var statCount int64
+		rowErr := queryResults.Scan(&dataKey, &statKey, &statCount)
+		if rowErr != nil {
+			continue
+		}
+		statsData.add(dataKey, statKey.String, statCount)
+	}
+	return statsData, queryResults.Err()
+}
+
+func splitList(scopes string) []string {
+	if len(scopes) == 0 {
+		return []string{}
+	}
+	return strings.Split(scopes, ",")
+}
+
+func scanSqliteApiKey(row sqlRow) (ApiKey, error) {
+	var responseData ApiKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullString
+	rowErr := row.Scan(&responseData.Id, &responseData.KeyId, &responseData.Name, &scopes, &responseData.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt, &responseData.RateLimit, &responseData.DailyQuota, &responseData.MonthlyQuota)
+	responseData.Scopes = splitList(scopes)
+	responseData.ExpiresAt = expiresAt.String
+	responseData.LastUsedAt = lastUsedAt.String
+	responseData.Revoked = revokedAt.Valid
+	return responseData, sqliteError(rowErr)
+}
+
+func (store *SqliteStore) GetApiKeyCaller(ctx context.Context, keyId string) (ApiCaller, string, error) {
+	var secretHash, scopes string
+	caller := ApiCaller{KeyId: keyId}
+	db_err := store.db.QueryRowContext(ctx, "SELECT secret_hash, scopes, COALESCE(rate_limit, 0), COALESCE(daily_quota, 0), COALESCE(monthly_quota, 0) FROM UrlRedirects_ApiKeys WHERE key_id=? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) LIMIT ?", keyId, sqliteNow(), dbLimit).Scan(&secretHash, &scopes, &caller.RateLimit, &caller.DailyQuota, &caller.MonthlyQuota)
+	caller.Scopes = splitList(scopes)
+	return caller, secretHash, sqliteError(db_err)
+}
+
+func (store *SqliteStore) TouchApiKey(ctx context.Context, keyId string) error {
+	_, db_err := store.db.ExecContext(ctx, "UPDATE UrlRedirects_ApiKeys SET last_used_at=? WHERE key_id=?", sqliteNow(), keyId)
+	return db_err
+}
+
+func (store *SqliteStore) InsertApiKey(ctx context.Context, keyId string, secretHash string, keyData ApiKeyData) (ApiKey, error) {
+	var expiresAt sql.NullString
+	if expiresTime := timeOrNil(keyData.ExpiresAt); expiresTime != nil {
+		expiresAt = sql.NullString{String: formatTimestamp(*expiresTime), Valid: true}
+	}
+	return scanSqliteApiKey(store.db.QueryRowContext(ctx, "INSERT INTO UrlRedirects_ApiKeys (key_id, name, secret_hash, scopes, created_at, expires_at, rate_limit, daily_quota, monthly_quota) VALUES (?,?,?,?,?,?,NULLIF(?,0),NULLIF(?,0),NULLIF(?,0)) RETURNING "+sqliteApiKeyColumns, keyId, keyData.Name, secretHash, strings.Join(keyData.Scopes, ","), sqliteNow(), expiresAt, keyData.RateLimit, keyData.DailyQuota, keyData.MonthlyQuota))
+}
+
+func (store *SqliteStore) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	responseData := []ApiKey{}
+	rows, db_err := store.db.QueryContext(ctx, "SELECT "+sqliteApiKeyColumns+" FROM UrlRedirects_ApiKeys ORDER BY id")
+	if db_err != nil {
+		return responseData, db_err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		temp, rowErr := scanSqliteApiKey(rows)
+		if rowErr == nil {
+			responseData = append(responseData, temp)
+		}
+	}
+	return responseData, rows.Err()
+}
+
+func (store *SqliteStore) RevokeApiKey(ctx context.Context, keyId string) (ApiKey, error) {
+	return scanSqliteApiKey(store.db.QueryRowContext(ctx, "UPDATE UrlRedirects_ApiKeys SET revoked_at=COALESCE(revoked_at, ?) WHERE key_id=? RETURNING "+sqliteApiKeyColumns, sqliteNow(), keyId))
+}
+
+func nullableJson(state []byte) sql.NullString {
+	return sql.NullString{String: string(state), Valid: state != nil}
+}
+
+func (store *SqliteStore) InsertAuditLog(ctx context.Context, auditLog AuditLog) error {
+	_, db_err := store.db.ExecContext(ctx, "INSERT INTO UrlRedirects_Audit (log_timestamp, actor, source_ip, request_id, method, action, redirect_id, redirect_path, before_state, after_state, status, result) VALUES (?,?,?,?,?,?,NULLIF(?,0),NULLIF(?,''),?,?,?,?)",
+		sqliteNow(), auditLog.Actor, auditLog.SourceIp, auditLog.RequestId, auditLog.Method, auditLog.Action, auditLog.RedirectId, auditLog.RedirectPath, nullableJson(auditLog.Before), nullableJson(auditLog.After), auditLog.Status, auditLog.Result)
+	return db_err
+}
+
+func (store *SqliteStore) ListAuditLogs(ctx context.Context, auditQuery AuditQuery, limit int, offset int) ([]AuditLog, error) {
+	responseData := []AuditLog{}
+	rows, db_err := store.db.QueryContext(ctx, "SELECT "+sqliteAuditColumns+` FROM UrlRedirects_Audit
+		WHERE log_timestamp BETWEEN ?1 AND ?2 AND (?3 = '' OR redirect_path = ?3) AND (?4 = '' OR actor = ?4) ORDER BY id DESC LIMIT ?5 OFFSET ?6`,
+		formatTimestamp(time.Unix(auditQuery.Start, 0)), formatTimestamp(time.Unix(auditQuery.End, 0)), auditQuery.Path, auditQuery.Actor, limit, offset)
+	if db_err != nil {
+		return responseData, db_err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var temp AuditLog
+		var sourceIp, requestId, redirectPath, beforeState, afterState sql.NullString
+		var redirectId sql.NullInt64
+		rowErr := rows.Scan(&temp.Id, &temp.Timestamp, &temp.Actor, &sourceIp, &requestId, &temp.Method, &temp.Action, &redirectId, &redirectPath, &beforeState, &afterState, &temp.Status, &temp.Result)
+		if rowErr == nil {
+			temp.SourceIp, temp.RequestId, temp.RedirectPath = sourceIp.String, requestId.String, redirectPath.String
+			temp.RedirectId = int(redirectId.Int64)
+			if beforeState.Valid {
+				temp.Before = []byte(beforeState.String)
+			}
+			if afterState.Valid {
+				temp.After = []byte(afterState.String)
+			}
+			responseData = append(responseData, temp)
+		}
+	}
+	return responseData, rows.Err()
+}
+
+func (store *SqliteStore) migrationDialect() string {
+	return sqliteBackend
+}
+
+func (store *SqliteStore) lockMigrations(ctx context.Context) (func(), error) {
+	return func() {}, nil
+}
+
+func (store *SqliteStore) appliedMigrationVersions(ctx context.Context) ([]int, error) {
+	if _, err := store.db.ExecContext(ctx, sqliteMigrationsSchema); err != nil {
+		return nil, err
+	}
+	rows, err := store.db.QueryContext(ctx, "SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	appliedVersions := []int{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		appliedVersions = append(appliedVersions, version)
+	}
+	return appliedVersions, rows.Err()
+}
+
+func (store *SqliteStore) applyMigration(ctx context.Context, migration Migration, up bool) error {
+	tx, err := store.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if up {
+		_, err = tx.ExecContext(ctx, migration.Up)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?,?,?)", migration.Version, migration.Name, sqliteNow())
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, migration.Down)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version=?", migration.Version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"archive/zip"
+	"html/template"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const staticArchiveName = "redirects-static.zip"
+const staticManifestFile = "manifest.json"
+const staticNotFoundFile = "404.html"
+
+var staticRedirectTemplate = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url={{.}}">
+<link rel="canonical" href="{{.}}">
+<title>Redirecting&hellip;</title>
+</head>
+<body>
+<p>Redirecting to <a href="{{.}}">{{.}}</a></p>
+</body>
+</html>
+`))
+
+var staticNotFoundTemplate = template.Must(template.New("notfound").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Not Found</title>
+</head>
+<body>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+type StaticManifestEntry struct {
+	Path       string `json:"path"`
+	Url        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	File       string `json:"file"`
+}
+
+type StaticManifest struct {
+	GeneratedAt string                `json:"generatedAt"`
+	NotFound    string                `json:"notFound"`
+	Count       int                   `json:"count"`
+	Redirects   []StaticManifestEntry `json:"redirects"`
+	Skipped     []string              `json:"skipped,omitempty"`
+}
+
+func staticRedirectFile(redirectPath string) (string, bool) {
+	if len(redirectPath) == 0 || path.Clean(redirectPath) != redirectPath || strings.HasPrefix(redirectPath, "/") || strings.HasPrefix(redirectPath, "..") || strings.ContainsAny(redirectPath, "\\?#") {
+		return "", false
+	}
+	return redirectPath + "/index.html", true
+}
+
+func exportStaticSite(store Store) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isInactive := false
+		filter := RedirectFilter{Inactive: &isInactive}
+		redirects, db_err := store.ListRedirects(r.Context(), RedirectQuery{RedirectFilter: filter, Limit: exportBatchSize})
+		if db_err != nil {
+			log.Println("exportStaticSite -> ", db_err.Error())
+			writeApiError(w, r, http.StatusInternalServerError, databaseErrorCode, dbError)
+			return
+		}
+		responseController := http.NewResponseController(w)
+		responseController.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+staticArchiveName+"\"")
+		w.WriteHeader(http.StatusOK)
+		archive := zip.NewWriter(w)
+		manifest := StaticManifest{GeneratedAt: formatTimestamp(time.Now()), NotFound: staticNotFoundFile, Redirects: []StaticManifestEntry{}}
+		for len(redirects) > 0 {
+			for _, redirect := range redirects {
+				if isRedirectExpired(redirect) {
+					continue
+				}
+				redirectFile, isPathSafe := staticRedirectFile(redirect.Path)
+				if !isPathSafe {
+					manifest.Skipped = append(manifest.Skipped, redirect.Path)
+					continue
+				}
+				fileWriter, zipErr := archive.Create(redirectFile)
+				if zipErr == nil {
+					zipErr = staticRedirectTemplate.Execute(fileWriter, buildUri(redirect.Url))
+				}
+				if zipErr != nil {
+					log.Println("exportStaticSite -> ", zipErr.Error())
+					panic(http.ErrAbortHandler)
+				}
+				manifest.Redirects = append(manifest.Redirects, StaticManifestEntry{Path: "/" + redirect.Path, Url: buildUri(redirect.Url), StatusCode: redirect.StatusCode, File: redirectFile})
+			}
+			if len(redirects) < exportBatchSize {
+				break
+			}
+			redirects, db_err = store.ListRedirects(r.Context(), RedirectQuery{RedirectFilter: filter, AfterId: redirects[len(redirects)-1].Id, Limit: exportBatchSize})
+			if db_err != nil {
+				log.Println("exportStaticSite -> ", db_err.Error())
+				panic(http.ErrAbortHandler)
+			}
+		}
+		manifest.Count = len(manifest.Redirects)
+		if fileWriter, zipErr := archive.Create(staticNotFoundFile); zipErr == nil {
+			staticNotFoundTemplate.Execute(fileWriter, notFoundMessage)
+		}
+		if fileWriter, zipErr := archive.Create(staticManifestFile); zipErr == nil {
+			fileWriter.Write(toJson(manifest))
+		}
+		if zipErr := archive.Close(); zipErr != nil {
+			log.Println("exportStaticSite -> ", zipErr.Error())
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestStaticExportAbortsOnStreamError(t *testing.T) {
+	store := newMemoryStore()
+	for i := range exportBatchSize {
+		store.InsertRedirect(context.Background(), Redirect{Path: "path" + strconv.Itoa(i), Url: "example.com"}, "")
+	}
+	server := httptest.NewServer(exportStaticSite(failingPageStore{store}))
+	defer server.Close()
+	response, requestErr := http.Get(server.URL)
+	if requestErr != nil {
+		t.Fatal(requestErr)
+	}
+	defer response.Body.Close()
+	if _, readErr := io.ReadAll(response.Body); readErr == nil {
+		t.Error("static export ended cleanly after a database error")
+	}
+}
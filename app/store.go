@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const postgresBackend = "postgres"
+const sqliteBackend = "sqlite"
+const memoryBackend = "memory"
+
+const timestampLayout = "2006-01-02 15:04:05.000000"
+
+var errNotFound = errors.New("record not found")
+var errConflict = errors.New("record already exists")
+var errDryRun = errors.New("dry run")
+var errDuplicateUrl = fmt.Errorf("canonical url: %w", errConflict)
+
+const sortById = "id"
+const sortByPath = "path"
+const sortByUpdatedAt = "updatedAt"
+const sortByCreatedAt = "createdAt"
+
+type RedirectFilter struct {
+	Inactive     *bool
+	Tag          string
+	Domain       string
+	Search       string
+	UpdatedSince time.Time
+}
+
+type RedirectQuery struct {
+	RedirectFilter
+	Sort       string
+	Descending bool
+	AfterValue string
+	AfterId    int
+	Limit      int
+}
+
+type SearchResult struct {
+	Redirect
+	Score float64
+}
+
+type RedirectStore interface {
+	GetRedirectUsingPath(ctx context.Context, path string) (Redirect, error)
+	GetRedirectUsingId(ctx context.Context, id int) (Redirect, error)
+	GetRedirectUsingCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error)
+	LockRedirect(ctx context.Context, id int) (Redirect, error)
+	LockCanonicalUrl(ctx context.Context, canonicalUrl string) (Redirect, error)
+	NextRedirectId(ctx context.Context) (int, error)
+	InsertRedirect(ctx context.Context, redirect Redirect, createdBy string) (Redirect, error)
+	UpdateRedirect(ctx context.Context, redirect Redirect) (Redirect, error)
+	ListRedirects(ctx context.Context, query RedirectQuery) ([]Redirect, error)
+	CountRedirects(ctx context.Context, filter RedirectFilter) (int, error)
+	SearchRedirects(ctx context.Context, query SearchQuery) ([]SearchResult, int, error)
+	CountRedirectsCreatedSince(ctx context.Context, createdBy string, since time.Time) (int, error)
+	BackfillCanonicalUrls(ctx context.Context, canonicalize func(string) string) (int, error)
+	RunInTransaction(ctx context.Context, run func(RedirectStore) error) error
+}
+
+type AnalyticsStore interface {
+	InsertAnalyticsLog(ctx context.Context, logEntry AnalyticsLog) error
+	GetStats(ctx context.Context, start time.Time, end time.Time) (LogStatsData, error)
+}
+
+type ApiKeyStore interface {
+	GetApiKeyCaller(ctx context.Context, keyId string) (ApiCaller, string, error)
+	TouchApiKey(ctx context.Context, keyId string) error
+	InsertApiKey(ctx context.Context, keyId string, secretHash string, keyData ApiKeyData) (ApiKey, error)
+	ListApiKeys(ctx context.Context) ([]ApiKey, error)
+	RevokeApiKey(ctx context.Context, keyId string) (ApiKey, error)
+}
+
+type AuditStore interface {
+	InsertAuditLog(ctx context.Context, auditLog AuditLog) error
+	ListAuditLogs(ctx context.Context, auditQuery AuditQuery, limit int, offset int) ([]AuditLog, error)
+}
+
+type Store interface {
+	RedirectStore
+	AnalyticsStore
+	ApiKeyStore
+	AuditStore
+	MigrationRunner
+	Close()
+}
+
+func getStorageBackend() string {
+	storageBackend := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_BACKEND")))
+	if len(storageBackend) == 0 {
+		return postgresBackend
+	}
+	return storageBackend
+}
+
+func connectStore() Store {
+	storageBackend := getStorageBackend()
+	var store Store
+	var storeErr error
+	switch storageBackend {
+	case postgresBackend:
+		store, storeErr = newPostgresStore(os.Getenv("DATABASE_URL"))
+	case sqliteBackend:
+		store, storeErr = newSqliteStore(os.Getenv("DATABASE_URL"))
+	case memoryBackend:
+		store = newMemoryStore()
+	default:
+		log.Fatalf("Unknown storage backend: %s\n", storageBackend)
+	}
+	if storeErr != nil {
+		log.Fatalf("Unable to connect to DB: %v\n", storeErr)
+	}
+	log.Println("Using storage backend", storageBackend)
+	return store
+}
+
+func (redirect Redirect) sortValue(sort string) string {
+	switch sort {
+	case sortByPath:
+		return redirect.Path
+	case sortByUpdatedAt:
+		return redirect.LastUpdated
+	case sortByCreatedAt:
+		return redirect.CreatedAt
+	}
+	return ""
+}
+
+func formatTimestamp(timestamp time.Time) string {
+	return timestamp.UTC().Format(timestampLayout)
+}
+
+func timeOrNil(unixTime int64) *time.Time {
+	if unixTime <= 0 {
+		return nil
+	}
+	timestamp := time.Unix(unixTime, 0)
+	return &timestamp
+}
+
+func (statsData *LogStatsData) add(dataKey string, statKey string, statCount any) {
+	dataItem := LogQueryData{StatKey: strings.TrimSpace(statKey), StatCount: statCount}
+	if len(dataItem.StatKey) <= 0 {
+		dataItem.StatKey = "Other"
+	}
+	switch {
+	case dataKey == "path":
+		statsData.Path = append(statsData.Path, dataItem)
+	case dataKey == "status":
+		statsData.Status = append(statsData.Status, dataItem)
+	case dataKey == "time":
+		statsData.Time = append(statsData.Time, dataItem)
+	}
+}
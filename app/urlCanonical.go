@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var envCanonicalSortQuery = os.Getenv("CANONICAL_SORT_QUERY")
+var envCanonicalStripTracking = os.Getenv("CANONICAL_STRIP_TRACKING")
+
+var trackingParams = []string{"fbclid", "gclid", "dclid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_ga", "_gl"}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+var hostProfile = idna.New(idna.MapForLookup(), idna.Transitional(false), idna.StrictDomainName(false))
+
+func isEnvEnabled(envValue string) bool {
+	isEnabled, _ := strconv.ParseBool(strings.TrimSpace(envValue))
+	return isEnabled
+}
+
+func isTrackingParam(param string) bool {
+	param = strings.ToLower(param)
+	return strings.HasPrefix(param, "utm_") || slices.Contains(trackingParams, param)
+}
+
+func removeDotSegments(path string) string {
+	segments := strings.Split(path, "/")
+	output := []string{}
+	for i, segment := range segments {
+		isLast := i == len(segments)-1
+		switch segment {
+		case ".":
+		case "..":
+			if len(output) > 1 {
+				output = output[:len(output)-1]
+			}
+		default:
+			output = append(output, segment)
+			continue
+		}
+		if isLast {
+			output = append(output, "")
+		}
+	}
+	canonicalPath := strings.Join(output, "/")
+	if !strings.HasPrefix(canonicalPath, "/") {
+		canonicalPath = "/" + canonicalPath
+	}
+	return canonicalPath
+}
+
+func normalizePercentEncoding(value string) string {
+	var normalized strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' || i+2 >= len(value) {
+			normalized.WriteByte(value[i])
+			continue
+		}
+		decoded, err := strconv.ParseUint(value[i+1:i+3], 16, 8)
+		switch {
+		case err != nil:
+			normalized.WriteByte(value[i])
+			continue
+		case isUnreservedByte(byte(decoded)):
+			normalized.WriteByte(byte(decoded))
+		default:
+			normalized.WriteString(strings.ToUpper(value[i : i+3]))
+		}
+		i += 2
+	}
+	return normalized.String()
+}
+
+func isUnreservedByte(b byte) bool {
+	return ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9') || strings.IndexByte("-._~", b) >= 0
+}
+
+func canonicalQuery(rawQuery string) string {
+	queryParams := []string{}
+	for _, queryParam := range strings.Split(rawQuery, "&") {
+		paramKey, _, _ := strings.Cut(queryParam, "=")
+		if len(queryParam) == 0 || (isEnvEnabled(envCanonicalStripTracking) && isTrackingParam(paramKey)) {
+			continue
+		}
+		queryParams = append(queryParams, queryParam)
+	}
+	if isEnvEnabled(envCanonicalSortQuery) {
+		slices.SortStableFunc(queryParams, func(x, y string) int {
+			xKey, _, _ := strings.Cut(x, "=")
+			yKey, _, _ := strings.Cut(y, "=")
+			return strings.Compare(xKey, yKey)
+		})
+	}
+	return strings.Join(queryParams, "&")
+}
+
+func canonicalizeUrl(uri string) (string, bool) {
+	if !strings.Contains(uri, "://") {
+		uri = httpsProtocol + uri
+	}
+	parsedUri, err := url.Parse(uri)
+	if err != nil || len(parsedUri.Hostname()) == 0 {
+		return errorMessage, false
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsedUri.Hostname()), ".")
+	if hostAddr, addrErr := netip.ParseAddr(host); addrErr == nil && hostAddr.Is6() {
+		host = "[" + hostAddr.String() + "]"
+	} else if host, err = hostProfile.ToASCII(host); err != nil {
+		return errorMessage, false
+	}
+	if port := parsedUri.Port(); len(port) > 0 && port != defaultPorts[strings.ToLower(parsedUri.Scheme)] {
+		host = host + ":" + port
+	}
+	canonicalUri := host + removeDotSegments(normalizePercentEncoding(parsedUri.EscapedPath()))
+	if query := canonicalQuery(normalizePercentEncoding(parsedUri.RawQuery)); len(query) > 0 {
+		canonicalUri = canonicalUri + "?" + query
+	}
+	return canonicalUri, true
+}
+
+func backfillCanonicalUrls(store RedirectStore) {
+	backfilled, err := store.BackfillCanonicalUrls(context.Background(), func(url string) string {
+		canonicalUrl, isUrlValid := canonicalizeUrl(url)
+		if !isUrlValid {
+			return url
+		}
+		return canonicalUrl
+	})
+	if err != nil {
+		log.Println("Canonical URL Backfill Error:", err)
+		return
+	}
+	if backfilled > 0 {
+		log.Println("Backfilled canonical URLs:", backfilled)
+	}
+}
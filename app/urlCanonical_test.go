@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestCanonicalizeUrlHosts(t *testing.T) {
+	for uri, wantCanonicalUrl := range map[string]string{
+		"https://my_host.example.com/a":  "my_host.example.com/a",
+		"https://_dmarc.Example.com/":    "_dmarc.example.com/",
+		"https://Bücher.example/":        "xn--bcher-kva.example/",
+		"https://example.com:443/x":      "example.com/x",
+		"http://[2001:DB8::1]:8080/path": "[2001:db8::1]:8080/path",
+	} {
+		canonicalUrl, isUrlValid := canonicalizeUrl(uri)
+		if !isUrlValid || canonicalUrl != wantCanonicalUrl {
+			t.Errorf("canonicalizeUrl(%q) = %q, %t, want %q", uri, canonicalUrl, isUrlValid, wantCanonicalUrl)
+		}
+	}
+}
@@ -11,7 +11,6 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -26,63 +25,88 @@ func notFound(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(notFoundMessage))
 }
 
-func initDB() *pgxpool.Pool {
-	dbpool, db_err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
-	if db_err != nil {
-		log.Fatalf("Unable to connect to DB: %v\n", db_err)
-		defer os.Exit(1)
-	}
-	_, db_init_err1 := dbpool.Exec(context.Background(), urlredirectSchema)
-	if db_init_err1 != nil {
-		log.Fatalf("Error creating URL Redirects table: %v\n", db_init_err1)
-		defer os.Exit(1)
+func initDB() Store {
+	store := connectStore()
+	if isAutoMigrateEnabled() {
+		if migrateErr := migrateUp(store, -1); migrateErr != nil {
+			log.Fatalf("Error migrating DB schema: %v\n", migrateErr)
+			defer os.Exit(1)
+		}
 	}
-	_, db_init_err2 := dbpool.Exec(context.Background(), urlredirectAnalyticsSchema)
-	if db_init_err2 != nil {
-		log.Fatalf("Error creating URL Redirects Analytics table: %v\n", db_init_err2)
+	if schemaErr := checkSchemaVersion(store); schemaErr != nil {
+		log.Fatalf("Error checking DB schema: %v\n", schemaErr)
 		defer os.Exit(1)
 	}
+	backfillCanonicalUrls(store)
 	log.Println("DB initialized successfully")
-	return dbpool
+	return store
 }
 
-func initRouter(dbpool *pgxpool.Pool) *chi.Mux {
+func initRouter(store Store) *chi.Mux {
 	router := chi.NewRouter()
 	apiRouter := chi.NewRouter()
-	router.Use(middleware.Heartbeat("/app/health"))
-	router.Use(logRequest(dbpool))
-	router.Use(httpRateLimit)
+	publicRateLimit := httpRateLimit()
+	router.Use(middleware.Heartbeat(healthCheckPath))
+	router.Use(middleware.RequestID)
+	router.Use(withClientIp)
+	router.Use(logRequest(store))
 	router.Use(prometheusMiddleware)
-	apiRouter.Use(verifyApiKey)
-	router.Use(middleware.AllowContentType("application/json"))
-	apiRouter.Get("/info/{id}", redirectInfo(dbpool))
-	apiRouter.Post("/create", addRedirect(dbpool))
-	apiRouter.Put("/update/{id}", updateRedirect(dbpool))
-	apiRouter.Patch("/fix", patchRedirect(dbpool))
-	apiRouter.Delete("/disable/{id}", deleteRedirect(dbpool))
-	apiRouter.Get("/list", listall(dbpool))
-	apiRouter.Post("/generate", generateRedirect(dbpool))
-	apiRouter.Post("/search", searchPath(dbpool))
-	apiRouter.Post("/check", redirectExists(dbpool))
-	apiRouter.Post("/stats", stats(dbpool))
-	router.Get("/*", handleRedirect(dbpool))
-	router.Get("/qr/*", getRedirectQRCode(dbpool))
-	router.Get("/notfound", notFound)
-	router.Get("/about", about)
+	apiRouter.Use(verifyApiKey(store))
+	apiRouter.Use(apiRateLimit())
+	apiRouter.Use(validateRequest(apiRouter))
+	router.Use(middleware.AllowContentType("application/json", "text/csv", "application/x-ndjson", "text/plain", mergePatchContentType))
+	apiRouter.With(requireScope(readScope)).Get("/info/{id}", redirectInfo(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope), enforceQuota(store)).Post("/create", addRedirect(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope)).Put("/update/{id}", updateRedirect(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope)).Patch("/fix", patchRedirect(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope)).Delete("/disable/{id}", deleteRedirect(store))
+	apiRouter.With(requireScope(readScope)).Get("/list", listall(store))
+	apiRouter.With(requireScope(readScope)).Get("/export", exportRedirects(store))
+	apiRouter.With(requireScope(readScope)).Get("/rules/{target}", exportRules(store))
+	apiRouter.With(requireScope(readScope)).Get("/static", exportStaticSite(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope), enforceQuota(store)).Post("/generate", generateRedirect(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope), enforceQuota(store)).Post("/import", importRedirects(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope)).Post("/batch", batchRedirects(store))
+	apiRouter.With(requireScope(readScope)).Get("/v2/redirects", listRedirectResources(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope), enforceQuota(store)).Post("/v2/redirects", createRedirectResource(store))
+	apiRouter.With(requireScope(readScope)).Get("/v2/redirects/{id}", getRedirectResource(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope)).Patch("/v2/redirects/{id}", patchRedirectResource(store))
+	apiRouter.With(auditAction(store), requireScope(writeScope)).Delete("/v2/redirects/{id}", disableRedirectResource(store))
+	apiRouter.With(requireScope(readScope)).Post("/search", searchPath(store))
+	apiRouter.With(requireScope(readScope)).Post("/find", findRedirects(store))
+	apiRouter.With(requireScope(readScope)).Post("/check", redirectExists(store))
+	apiRouter.With(requireScope(statsScope)).Post("/stats", stats(store))
+	apiRouter.With(auditAction(store), requireScope(adminScope)).Post("/keys", createApiKey(store))
+	apiRouter.With(requireScope(adminScope)).Get("/keys", listApiKeys(store))
+	apiRouter.With(requireScope(adminScope)).Post("/audit", auditLogs(store))
+	apiRouter.With(auditAction(store), requireScope(adminScope)).Delete("/keys/{keyId}", revokeApiKey(store))
+	router.With(publicRateLimit).Get("/*", handleRedirect(store))
+	router.With(publicRateLimit).Get("/qr/*", getRedirectQRCode(store))
+	router.With(publicRateLimit).Get("/notfound", notFound)
+	router.With(publicRateLimit).Get("/about", about)
 	router.NotFound(notFound)
 	router.MethodNotAllowed(notFound)
-	router.Mount("/redirector", apiRouter)
+	router.With(publicRateLimit).Get(openApiPath, openApiSpec)
+	router.With(publicRateLimit).Get(apiDocsPath, apiDocs)
+	router.Mount(apiBasePath, apiRouter)
 	router.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	reserveRoutePaths(router)
 	return router
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
 	serverAddr := serverListenerAddress()
-	dbpool := initDB()
-	defer dbpool.Close()
+	store := initDB()
+	defer store.Close()
 	initMetrics()
-	startAnalyticsWorker(dbpool)
-	router := initRouter(dbpool)
+	initJwtAuth()
+	startAnalyticsWorker(store)
+	router := initRouter(store)
+	reportReservedPathCollisions(store)
 
 	server := &http.Server{
 		Addr:         serverAddr,
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"log"
 	"net"
@@ -13,10 +12,11 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"math/rand"
-
-	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/text/unicode/norm"
 )
 
 var errorBytes []byte
@@ -24,12 +24,29 @@ var errorBytes []byte
 const errorMessage = "Error"
 const notFoundMessage = "Are you Lost??"
 const alreadyExistMessage = "URL Redirect Exists"
+const generateConflictMessage = "Unable to Generate Unique Path"
 const notExistMessage = "URL Redirect for Path doesn't Exists"
+const apiKeyNotExistMessage = "API Key doesn't Exists"
+const insufficientScopeMessage = "Insufficient Scope"
+const quotaExceededMessage = "Quota Exceeded"
 const badRequest = "Bad Request"
+const unauthorizedMessage = "Unauthorized"
+const rateLimitedMessage = "Rate Limit Exceeded"
+const reservedPathMessage = "Path is Reserved"
+const preconditionFailedMessage = "URL Redirect was Modified"
+const requestTooLargeMessage = "Request Body Too Large"
+const unsupportedRule = "Unsupported Rule: "
 const dbError = "DataBase Error"
 const internalError = "Internal Error"
 const dbLimit = 1
 const pageLimit = 10
+const maxPageLimit = 100
+const maxTitleLength = 255
+const maxFolderLength = 255
+const maxRequestBodySize = 1 << 20
+const maxImportBodySize = 32 << 20
+const pathPunctuation = "-_.~"
+const generateAttempts = 5
 const httpsProtocol = "https://"
 
 var metricsList = map[string]string{
@@ -43,36 +60,40 @@ var metricsList = map[string]string{
 	"/sched/goroutines:goroutines":        "go_goroutines",
 }
 
+var redirectStatusCodes = []int{http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect}
 var pathsToSkipLogging = []string{"/metrics", "/favicon.ico"}
+var maxPathLength = min(migratedPathLength(postgresBackend), migratedPathLength(sqliteBackend))
 var apiKey = os.Getenv("API_KEY")
 var envHttpRateLimit = os.Getenv("HTTP_RATE_LIMIT")
+var envApiRateLimit = os.Getenv("API_RATE_LIMIT")
+var envAuthFailureRateLimit = os.Getenv("AUTH_FAILURE_RATE_LIMIT")
 var logAdditionalHeaders = strings.Split(os.Getenv("LOG_ADDITIONAL_HEADERS"), ",")
+var envPathAllowUnicode = os.Getenv("PATH_ALLOW_UNICODE")
+
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
 
-const urlredirectSchema = `CREATE TABLE IF NOT EXISTS UrlRedirects (
-    id SERIAL PRIMARY KEY,
-    path VARCHAR(29) NOT NULL UNIQUE,
-    url VARCHAR(100) NOT NULL,
-    updated_at TIMESTAMP NOT NULL DEFAULT now(),
-    inactive BOOLEAN NOT NULL DEFAULT FALSE
-);
-CREATE INDEX IF NOT EXISTS idx_urlredirects_url ON UrlRedirects(url);`
-
-const urlredirectAnalyticsSchema = `CREATE TABLE IF NOT EXISTS UrlRedirects_Analytics (
-  id SERIAL PRIMARY KEY,
-  path VARCHAR(100) NOT NULL,
-  log_timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
-  status int NOT NULL,
-  processing_time bigint,
-  additional_headers text
-);
-CREATE INDEX IF NOT EXISTS idx_analytics_timestamp ON UrlRedirects_Analytics(log_timestamp);`
+func (fieldErr *FieldError) Error() string {
+	return fieldErr.Field + ": " + fieldErr.Message
+}
 
 type Redirect struct {
-	Id          int    `json:"id,omitempty"`
-	Path        string `json:"path,omitempty"`
-	Url         string `json:"url,omitempty"`
-	LastUpdated string `json:"lastUpdated,omitempty"`
-	Inactive    bool   `json:"inactive,omitempty"`
+	Id             int      `json:"id,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	Url            string   `json:"url,omitempty"`
+	LastUpdated    string   `json:"lastUpdated,omitempty"`
+	Inactive       bool     `json:"inactive,omitempty"`
+	CanonicalUrl   string   `json:"canonicalUrl,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	StatusCode     int      `json:"statusCode,omitempty"`
+	Title          string   `json:"title,omitempty"`
+	CreatedAt      string   `json:"createdAt,omitempty"`
+	ImportedClicks int64    `json:"importedClicks,omitempty"`
+	Folder         string   `json:"folder,omitempty"`
+	ExpiresAt      string   `json:"expiresAt,omitempty"`
 }
 
 type LogStatsData struct {
@@ -87,14 +108,54 @@ type LogQueryData struct {
 }
 
 type UrlData struct {
-	Url  string `json:"url,omitempty"`
-	Path string `json:"path,omitempty"`
+	Url            string   `json:"url,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	AllowDuplicate bool     `json:"allowDuplicate,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	StatusCode     int      `json:"statusCode,omitempty"`
+	Title          string   `json:"title,omitempty"`
+}
+
+type SearchQuery struct {
+	Text          string    `json:"query,omitempty"`
+	Fields        []string  `json:"fields,omitempty"`
+	Inactive      *bool     `json:"inactive,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	StatusCode    int       `json:"statusCode,omitempty"`
+	CreatedAfter  time.Time `json:"createdAfter,omitempty"`
+	CreatedBefore time.Time `json:"createdBefore,omitempty"`
+	UpdatedAfter  time.Time `json:"updatedAfter,omitempty"`
+	UpdatedBefore time.Time `json:"updatedBefore,omitempty"`
+	Limit         int       `json:"limit,omitempty"`
+	Offset        int       `json:"offset,omitempty"`
+}
+
+type SearchMatch struct {
+	Redirect   Redirect          `json:"redirect"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type SearchResponse struct {
+	Total   int           `json:"total"`
+	Results []SearchMatch `json:"results"`
 }
 
 type OpsData struct {
 	Data string `json:"data,omitempty"`
 }
 
+type GenerateData struct {
+	Data           string   `json:"data,omitempty"`
+	Strategy       string   `json:"strategy,omitempty"`
+	Length         int      `json:"length,omitempty"`
+	Reuse          bool     `json:"reuse,omitempty"`
+	AllowDuplicate bool     `json:"allowDuplicate,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	StatusCode     int      `json:"statusCode,omitempty"`
+	Title          string   `json:"title,omitempty"`
+}
+
 type StatsTime struct {
 	Start int64 `json:"start,omitempty"`
 	End   int64 `json:"end,omitempty"`
@@ -118,45 +179,108 @@ func validateAndFormatPath(path string) (string, bool) {
 	if err != nil {
 		return errorMessage, false
 	}
-	formattedPath = strings.Trim(validPath.Path, "/")
+	formattedPath = norm.NFC.String(strings.Trim(validPath.Path, "/"))
 	return formattedPath, err == nil
 }
 
-func getRedirectUsingPath(path string, db *pgxpool.Pool) (Redirect, error) {
-	var responseData Redirect
-	db_err := db.QueryRow(context.Background(), "SELECT id, path, url, updated_at::TEXT, inactive FROM UrlRedirects WHERE path=$1 LIMIT $2", path, dbLimit).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
-	if db_err != nil {
-		return responseData, db_err
+func isPathUnicodeAllowed() bool {
+	allowUnicode, parseErr := strconv.ParseBool(strings.TrimSpace(envPathAllowUnicode))
+	return parseErr == nil && allowUnicode
+}
+
+func isPathRuneValid(r rune, allowUnicode bool) bool {
+	if r < utf8.RuneSelf {
+		return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') || strings.ContainsRune(pathPunctuation, r)
+	}
+	return allowUnicode && (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r))
+}
+
+func validateRedirectPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", &FieldError{Field: "path", Message: "must start with /"}
+	}
+	validPath, err := url.ParseRequestURI(path)
+	if err != nil {
+		return "", &FieldError{Field: "path", Message: "must be a valid URL path"}
+	}
+	if len(validPath.RawQuery) > 0 || validPath.ForceQuery {
+		return "", &FieldError{Field: "path", Message: "must not contain a query string"}
+	}
+	formattedPath := norm.NFC.String(strings.Trim(validPath.Path, "/"))
+	switch {
+	case len(formattedPath) == 0:
+		return "", &FieldError{Field: "path", Message: "must not be empty"}
+	case utf8.RuneCountInString(formattedPath) > maxPathLength:
+		return "", &FieldError{Field: "path", Message: "must be at most " + strconv.Itoa(maxPathLength) + " characters"}
+	}
+	allowUnicode := isPathUnicodeAllowed()
+	for _, segment := range strings.Split(formattedPath, "/") {
+		if len(segment) == 0 || segment == "." || segment == ".." {
+			return "", &FieldError{Field: "path", Message: "must not contain empty, . or .. segments"}
+		}
+		for _, r := range segment {
+			if !isPathRuneValid(r, allowUnicode) {
+				return "", &FieldError{Field: "path", Message: "contains invalid character " + strconv.QuoteRune(r)}
+			}
+		}
+	}
+	return formattedPath, nil
+}
+
+func normalizeTags(tags []string) []string {
+	normalizedTags := []string{}
+	for _, tag := range tags {
+		for _, splitTag := range strings.Split(tag, ",") {
+			splitTag = strings.ToLower(strings.TrimSpace(splitTag))
+			if len(splitTag) > 0 && !slices.Contains(normalizedTags, splitTag) {
+				normalizedTags = append(normalizedTags, splitTag)
+			}
+		}
 	}
-	return responseData, nil
+	slices.Sort(normalizedTags)
+	return normalizedTags
 }
 
-func getRedirectUsingId(id int, db *pgxpool.Pool) (Redirect, error) {
-	var responseData Redirect
-	db_err := db.QueryRow(context.Background(), "SELECT id, path, url, updated_at::TEXT, inactive FROM UrlRedirects WHERE id=$1 LIMIT $2", id, dbLimit).Scan(&responseData.Id, &responseData.Path, &responseData.Url, &responseData.LastUpdated, &responseData.Inactive)
-	if db_err != nil {
-		return responseData, db_err
+func mergeTags(requestTags []string, existingTags []string) []string {
+	if requestTags == nil {
+		return existingTags
 	}
-	return responseData, nil
+	return normalizeTags(requestTags)
 }
 
-func doesUrlExists(url string, db *pgxpool.Pool) bool {
-	var possibleId int
-	db_err := db.QueryRow(context.Background(), "SELECT id FROM UrlRedirects WHERE url=$1 LIMIT $2", url, dbLimit).Scan(&possibleId)
-	return db_err == nil
+func isTitleValid(title string) bool {
+	return utf8.RuneCountInString(strings.TrimSpace(title)) <= maxTitleLength
 }
 
-func generateShortRedirectPath() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	length, parseErr := strconv.Atoi(os.Getenv("PATH_LENGTH"))
-	if parseErr != nil {
-		length = 9
+func mergeTitle(requestTitle string, existingTitle string) string {
+	if requestTitle = strings.TrimSpace(requestTitle); len(requestTitle) > 0 {
+		return requestTitle
 	}
-	shortPath := make([]byte, length)
-	for i := range shortPath {
-		shortPath[i] = charset[rand.Intn(len(charset))]
+	return existingTitle
+}
+
+func normalizeFolder(folder string) (string, bool) {
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	return folder, utf8.RuneCountInString(folder) <= maxFolderLength && !strings.Contains(folder, "//")
+}
+
+func isRedirectExpired(redirect Redirect) bool {
+	expiresAt, parseErr := time.Parse(time.DateTime, redirect.ExpiresAt)
+	return parseErr == nil && !expiresAt.After(time.Now().UTC())
+}
+
+func isRedirectStatusValid(statusCode int) bool {
+	return statusCode == 0 || slices.Contains(redirectStatusCodes, statusCode)
+}
+
+func redirectStatus(statusCode int, existingStatusCode int) int {
+	if statusCode == 0 {
+		statusCode = existingStatusCode
+	}
+	if statusCode == 0 {
+		return http.StatusFound
 	}
-	return string(shortPath)
+	return statusCode
 }
 
 func buildUri(url string) string {
@@ -178,7 +302,7 @@ func getRequestFunction(path string, statusCode int) (string, bool) {
 	isApiRequest := apiRegex.MatchString(path)
 	httpStatusText := strings.ToLower(http.StatusText(statusCode))
 	requestFunction = strings.ReplaceAll(httpStatusText, " ", "_")
-	if statusCode == http.StatusFound || statusCode == http.StatusNotFound {
+	if slices.Contains(redirectStatusCodes, statusCode) || statusCode == http.StatusNotFound {
 		requestFunction = "redirect_" + requestFunction
 	}
 	if isApiRequest {
@@ -238,15 +362,27 @@ func skipLogging(path string) bool {
 	return slices.Contains(pathsToSkipLogging, path)
 }
 
-func getHttpRateLimit() int {
-	customRateLimit, customRateLimitErr := strconv.Atoi(strings.TrimSpace(envHttpRateLimit))
-	if customRateLimitErr == nil {
+func parseRateLimit(envRateLimit string) int {
+	customRateLimit, customRateLimitErr := strconv.Atoi(strings.TrimSpace(envRateLimit))
+	if customRateLimitErr == nil && customRateLimit > 0 {
 		return customRateLimit
 	} else {
 		return 9
 	}
 }
 
+func getHttpRateLimit() int {
+	return parseRateLimit(envHttpRateLimit)
+}
+
+func getApiRateLimit() int {
+	return parseRateLimit(envApiRateLimit)
+}
+
+func getAuthFailureRateLimit() int {
+	return parseRateLimit(envAuthFailureRateLimit)
+}
+
 func serverListenerAddress() string {
 	addrHost := strings.TrimSpace(os.Getenv("HOST"))
 	addrPort := strings.TrimSpace(os.Getenv("PORT"))
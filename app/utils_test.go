@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaxPathLengthMatchesMigrations(t *testing.T) {
+	for _, dialect := range []string{postgresBackend, sqliteBackend} {
+		if pathLength := migratedPathLength(dialect); pathLength != maxPathLength {
+			t.Errorf("%s: UrlRedirects.path is VARCHAR(%d), maxPathLength is %d", dialect, pathLength, maxPathLength)
+		}
+	}
+}
+
+func TestValidateRedirectPathLength(t *testing.T) {
+	if _, pathErr := validateRedirectPath("/" + strings.Repeat("a", maxPathLength)); pathErr != nil {
+		t.Errorf("path at the limit rejected: %v", pathErr)
+	}
+	if _, pathErr := validateRedirectPath("/" + strings.Repeat("a", maxPathLength+1)); pathErr == nil {
+		t.Error("path over the limit accepted")
+	}
+}
+
+func TestPathUnicodeDefault(t *testing.T) {
+	previousAllowUnicode := envPathAllowUnicode
+	t.Cleanup(func() { envPathAllowUnicode = previousAllowUnicode })
+	for value, isAllowed := range map[string]bool{"": false, "invalid": false, "false": false, "true": true} {
+		envPathAllowUnicode = value
+		if isPathUnicodeAllowed() != isAllowed {
+			t.Errorf("PATH_ALLOW_UNICODE=%q: allowed = %t", value, !isAllowed)
+		}
+	}
+	envPathAllowUnicode = ""
+	if _, pathErr := validateRedirectPath("/café"); pathErr == nil {
+		t.Error("unicode path accepted by default")
+	}
+}
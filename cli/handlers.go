@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -30,13 +34,32 @@ func getUrlRedirect(cCtx *cli.Context) error {
 	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "info/" + strconv.Itoa(id)
 	res := apiService(http.MethodGet, endPoint, nil)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
 	json.NewDecoder(res.Body).Decode(&redirectData)
 	consoleDataWriter(redirectData)
 	return nil
 }
 
+func dryRunQuery(cCtx *cli.Context) string {
+	if cCtx.Bool("dry-run") {
+		return "?dryRun=true"
+	}
+	return ""
+}
+
+func consoleRedirectResponse(cCtx *cli.Context, res *http.Response) {
+	if cCtx.Bool("dry-run") {
+		var dryRunResult DryRunResult
+		json.NewDecoder(res.Body).Decode(&dryRunResult)
+		consoleDryRunWriter(dryRunResult)
+		return
+	}
+	var redirectData Redirect
+	json.NewDecoder(res.Body).Decode(&redirectData)
+	consoleDataWriter(redirectData)
+}
+
 func addUrlRedirect(cCtx *cli.Context) error {
 	path, uri := cCtx.Args().Get(0), cCtx.Args().Get(1)
 	_, pathErr := url.Parse(path)
@@ -44,16 +67,14 @@ func addUrlRedirect(cCtx *cli.Context) error {
 	if pathErr != nil || uriErr != nil {
 		respondAndExit("Args Error", pathErr, uriErr)
 	}
-	var redirectData Redirect
-	reqBody := UrlData{Url: uri, Path: path}
-	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "create"
+	reqBody := UrlData{Url: uri, Path: path, AllowDuplicate: cCtx.Bool("allow-duplicate"), Tags: cCtx.StringSlice("tag"), StatusCode: cCtx.Int("status-code"), Title: cCtx.String("title")}
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "create" + dryRunQuery(cCtx)
 	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
 	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
-	json.NewDecoder(res.Body).Decode(&redirectData)
-	consoleDataWriter(redirectData)
+	consoleRedirectResponse(cCtx, res)
 	return nil
 }
 
@@ -65,16 +86,15 @@ func updateUrlRedirect(cCtx *cli.Context) error {
 	if id < 0 || pathErr != nil || uriErr != nil {
 		respondAndExit("Args Error", id, pathErr, uriErr)
 	}
-	var redirectData Redirect
-	reqBody := Redirect{Id: id, Url: uri, Path: path, LastUpdated: time.Now().Format("YYYY-MM-DD hh:mm:ss"), Inactive: false}
-	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "update/" + strconv.Itoa(id)
+	reqBody := Redirect{Id: id, Url: uri, Path: path, LastUpdated: time.Now().Format("YYYY-MM-DD hh:mm:ss"), Inactive: false, Tags: cCtx.StringSlice("tag"), StatusCode: cCtx.Int("status-code"), Title: cCtx.String("title")}
+	queryParams := url.Values{"allowDuplicate": {strconv.FormatBool(cCtx.Bool("allow-duplicate"))}, "dryRun": {strconv.FormatBool(cCtx.Bool("dry-run"))}}
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "update/" + strconv.Itoa(id) + "?" + queryParams.Encode()
 	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
 	res := apiService(http.MethodPut, endPoint, reqBodyBytes)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
-	json.NewDecoder(res.Body).Decode(&redirectData)
-	consoleDataWriter(redirectData)
+	consoleRedirectResponse(cCtx, res)
 	return nil
 }
 
@@ -85,16 +105,14 @@ func fixUrlRedirect(cCtx *cli.Context) error {
 	if pathErr != nil || uriErr != nil {
 		respondAndExit("Args Error", pathErr, uriErr)
 	}
-	var redirectData Redirect
-	reqBody := UrlData{Url: uri, Path: path}
-	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "fix"
+	reqBody := UrlData{Url: uri, Path: path, AllowDuplicate: cCtx.Bool("allow-duplicate"), Tags: cCtx.StringSlice("tag"), StatusCode: cCtx.Int("status-code"), Title: cCtx.String("title")}
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "fix" + dryRunQuery(cCtx)
 	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
 	res := apiService(http.MethodPatch, endPoint, reqBodyBytes)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
-	json.NewDecoder(res.Body).Decode(&redirectData)
-	consoleDataWriter(redirectData)
+	consoleRedirectResponse(cCtx, res)
 	return nil
 }
 
@@ -103,57 +121,84 @@ func disableUrlRedirect(cCtx *cli.Context) error {
 	if id < 0 {
 		respondAndExit("Args Error", id)
 	}
-	var redirectData Redirect
-	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "disable/" + strconv.Itoa(id)
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "disable/" + strconv.Itoa(id) + dryRunQuery(cCtx)
 	res := apiService(http.MethodDelete, endPoint, nil)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
-	json.NewDecoder(res.Body).Decode(&redirectData)
-	consoleDataWriter(redirectData)
+	consoleRedirectResponse(cCtx, res)
 	return nil
 }
 
-func listUrlRedirects(cCtx *cli.Context) error {
-	page := cCtx.Value("page").(int)
-	if page > 0 {
-		page = page * 10
-	} else {
-		page = 0
+func fetchRedirectPages(cCtx *cli.Context, method string, endPoint string, reqBody []byte) {
+	queryParams := url.Values{}
+	for _, flagName := range []string{"limit", "sort", "status", "tag", "domain"} {
+		if cCtx.IsSet(flagName) {
+			queryParams.Set(flagName, cCtx.String(flagName))
+		}
 	}
 	var redirectDataList []Redirect
-	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "list?page=" + strconv.Itoa(page)
-	res := apiService(http.MethodGet, endPoint, nil)
-	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+	var totalCount string
+	nextCursor := cCtx.String("cursor")
+	for {
+		if len(nextCursor) > 0 {
+			queryParams.Set("cursor", nextCursor)
+		}
+		res := apiService(method, endPoint+"?"+queryParams.Encode(), bytes.NewReader(reqBody))
+		if res.StatusCode != http.StatusOK {
+			respondWithApiError(res)
+		}
+		var redirectPage []Redirect
+		json.NewDecoder(res.Body).Decode(&redirectPage)
+		res.Body.Close()
+		redirectDataList = append(redirectDataList, redirectPage...)
+		totalCount, nextCursor = res.Header.Get("X-Total-Count"), res.Header.Get("X-Next-Cursor")
+		if !cCtx.Bool("all") || len(nextCursor) == 0 {
+			break
+		}
 	}
-	json.NewDecoder(res.Body).Decode(&redirectDataList)
-	consoleDataListWriter(redirectDataList)
+	consoleDataListWriter(redirectDataList, totalCount, nextCursor)
+}
+
+func listUrlRedirects(cCtx *cli.Context) error {
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "list"
+	fetchRedirectPages(cCtx, http.MethodGet, endPoint, nil)
 	return nil
 }
 
 func searchUrlRedirect(cCtx *cli.Context) error {
 	path := cCtx.Args().Get(0)
-	page := cCtx.Value("page").(int)
 	_, pathErr := url.Parse(path)
 	if pathErr != nil {
 		respondAndExit("Args Error", pathErr)
 	}
-	if page > 0 {
-		page = page * 10
-	} else {
-		page = 0
-	}
-	var redirectDataList []Redirect
 	reqBody := OpsData{Data: path}
-	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "search?page=" + strconv.Itoa(page)
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "search"
+	fetchRedirectPages(cCtx, http.MethodPost, endPoint, toJson(reqBody))
+	return nil
+}
+
+func findUrlRedirects(cCtx *cli.Context) error {
+	reqBody := SearchQuery{Text: cCtx.Args().First(), Fields: cCtx.StringSlice("field"), Tags: cCtx.StringSlice("tag"), StatusCode: cCtx.Int("status-code"), Limit: cCtx.Int("limit"), Offset: cCtx.Int("offset")}
+	reqBody.CreatedAfter, reqBody.CreatedBefore = cCtx.Timestamp("created-after"), cCtx.Timestamp("created-before")
+	reqBody.UpdatedAfter, reqBody.UpdatedBefore = cCtx.Timestamp("updated-after"), cCtx.Timestamp("updated-before")
+	switch cCtx.String("status") {
+	case "", "all":
+	case "active", "inactive":
+		isInactive := cCtx.String("status") == "inactive"
+		reqBody.Inactive = &isInactive
+	default:
+		respondAndExit("Args Error", cCtx.String("status"))
+	}
+	var searchResponse SearchResponse
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "find"
 	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
 	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
-	json.NewDecoder(res.Body).Decode(&redirectDataList)
-	consoleDataListWriter(redirectDataList)
+	json.NewDecoder(res.Body).Decode(&searchResponse)
+	consoleSearchWriter(searchResponse, reqBody.Offset)
 	return nil
 }
 
@@ -169,7 +214,7 @@ func urlRedirectExists(cCtx *cli.Context) error {
 	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
 	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
 	json.NewDecoder(res.Body).Decode(&redirectData)
 	consoleDataWriter(redirectData)
@@ -182,16 +227,151 @@ func generateShortRedirect(cCtx *cli.Context) error {
 	if uriErr != nil {
 		respondAndExit("Args Error", uriErr)
 	}
-	var redirectData Redirect
-	reqBody := OpsData{Data: uri}
-	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "generate"
+	reqBody := GenerateData{Data: uri, Strategy: cCtx.String("strategy"), Length: cCtx.Int("length"), Reuse: cCtx.Bool("reuse"), AllowDuplicate: cCtx.Bool("allow-duplicate"), Tags: cCtx.StringSlice("tag"), StatusCode: cCtx.Int("status-code"), Title: cCtx.String("title")}
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "generate" + dryRunQuery(cCtx)
 	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
 	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+		respondWithApiError(res)
+	}
+	consoleRedirectResponse(cCtx, res)
+	return nil
+}
+
+func batchUrlRedirects(cCtx *cli.Context) error {
+	reqBody := BatchRequest{Action: cCtx.Args().First(), Tags: cCtx.StringSlice("tag"), Folder: cCtx.String("folder"), StatusCode: cCtx.Int("status-code")}
+	for _, arg := range cCtx.Args().Tail() {
+		id, idErr := strconv.Atoi(arg)
+		if idErr != nil || id <= 0 {
+			respondAndExit("Args Error", arg)
+		}
+		reqBody.Ids = append(reqBody.Ids, id)
+	}
+	if expiresAt := cCtx.Timestamp("expires-at"); expiresAt != nil {
+		reqBody.ExpiresAt = expiresAt.Unix()
+	}
+	queryParams := url.Values{"dryRun": {strconv.FormatBool(cCtx.Bool("dry-run"))}}
+	for _, filterName := range []string{"status", "tag", "domain"} {
+		if cCtx.IsSet("where-" + filterName) {
+			queryParams.Set(filterName, cCtx.String("where-"+filterName))
+		}
+	}
+	if since := cCtx.String("where-updated-since"); len(since) > 0 {
+		updatedSince, sinceErr := parseSince(since)
+		if sinceErr != nil {
+			respondAndExit("Args Error", sinceErr)
+		}
+		queryParams.Set("updatedSince", strconv.FormatInt(updatedSince, 10))
+	}
+	var batchReport BatchReport
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "batch?" + queryParams.Encode()
+	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
+	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusUnprocessableEntity {
+		respondWithApiError(res)
+	}
+	json.NewDecoder(res.Body).Decode(&batchReport)
+	consoleBatchWriter(batchReport)
+	return nil
+}
+
+func importUrlRedirects(cCtx *cli.Context) error {
+	filePath := cCtx.Args().Get(0)
+	importFile, fileErr := os.Open(filePath)
+	if fileErr != nil {
+		respondAndExit("Args Error", fileErr)
+	}
+	defer importFile.Close()
+	importFormat := cCtx.String("format")
+	if len(importFormat) == 0 {
+		switch strings.ToLower(filepath.Ext(filePath)) {
+		case ".csv":
+			importFormat = "csv"
+		case ".ndjson", ".jsonl":
+			importFormat = "ndjson"
+		case ".conf", ".htaccess":
+			importFormat = "apache"
+		default:
+			importFormat = "json"
+		}
+	}
+	queryParams := url.Values{"mode": {cCtx.String("mode")}, "format": {importFormat}, "allowDuplicate": {strconv.FormatBool(cCtx.Bool("allow-duplicate"))}, "dryRun": {strconv.FormatBool(cCtx.Bool("dry-run"))}}
+	var importReport ImportReport
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "import?" + queryParams.Encode()
+	res := apiService(http.MethodPost, endPoint, importFile)
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusUnprocessableEntity {
+		respondWithApiError(res)
+	}
+	json.NewDecoder(res.Body).Decode(&importReport)
+	consoleImportWriter(importReport)
+	return nil
+}
+
+func exportUrlRedirects(cCtx *cli.Context) error {
+	outputPath := cCtx.String("output")
+	exportFormat := cCtx.String("format")
+	if len(exportFormat) == 0 {
+		exportFormat = strings.TrimPrefix(strings.ToLower(filepath.Ext(outputPath)), ".")
+		if exportFormat == "jsonl" {
+			exportFormat = "ndjson"
+		} else if exportFormat == "yml" {
+			exportFormat = "yaml"
+		}
+	}
+	queryParams := url.Values{"format": {exportFormat}, "status": {cCtx.String("status")}, "tag": {cCtx.String("tag")}}
+	if since := cCtx.String("since"); len(since) > 0 {
+		updatedSince, sinceErr := parseSince(since)
+		if sinceErr != nil {
+			respondAndExit("Args Error", sinceErr)
+		}
+		queryParams.Set("updatedSince", strconv.FormatInt(updatedSince, 10))
+	}
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "export?" + queryParams.Encode()
+	res := apiService(http.MethodGet, endPoint, nil)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
-	json.NewDecoder(res.Body).Decode(&redirectData)
-	consoleDataWriter(redirectData)
+	defer res.Body.Close()
+	writeOutput(outputPath, res.Body)
+	return nil
+}
+
+func exportUrlRedirectRules(cCtx *cli.Context) error {
+	target := cCtx.Args().Get(0)
+	if len(target) == 0 {
+		respondAndExit("Args Error", target)
+	}
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "rules/" + url.PathEscape(target)
+	res := apiService(http.MethodGet, endPoint, nil)
+	if res.StatusCode != http.StatusOK {
+		respondWithApiError(res)
+	}
+	defer res.Body.Close()
+	writeOutput(cCtx.String("output"), res.Body)
+	return nil
+}
+
+func exportStaticSite(cCtx *cli.Context) error {
+	outputPath := cCtx.String("output")
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "static"
+	res := apiService(http.MethodGet, endPoint, nil)
+	if res.StatusCode != http.StatusOK {
+		respondWithApiError(res)
+	}
+	defer res.Body.Close()
+	if strings.EqualFold(filepath.Ext(outputPath), ".zip") {
+		writeOutput(outputPath, res.Body)
+		return nil
+	}
+	archiveData, readErr := io.ReadAll(res.Body)
+	if readErr != nil {
+		respondAndExit("Export Failed", readErr)
+	}
+	fileCount, extractErr := extractArchive(outputPath, archiveData)
+	if extractErr != nil {
+		respondAndExit("Export Failed", extractErr)
+	}
+	fmt.Printf("Wrote %d files to %s\n", fileCount, outputPath)
 	return nil
 }
 
@@ -203,9 +383,77 @@ func getRedirectStats(cCtx *cli.Context) error {
 	var LogStatsDataList LogStatsDataList
 	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
 	if res.StatusCode != http.StatusOK {
-		respondAndExit(res.Status)
+		respondWithApiError(res)
 	}
 	json.NewDecoder(res.Body).Decode(&LogStatsDataList)
 	consoleStatsWriter(LogStatsDataList)
 	return nil
 }
+
+func createApiKey(cCtx *cli.Context) error {
+	name := cCtx.Args().Get(0)
+	scopes := cCtx.StringSlice("scope")
+	days := cCtx.Int("days")
+	rateLimit, dailyQuota, monthlyQuota := cCtx.Int("rate-limit"), cCtx.Int("daily-quota"), cCtx.Int("monthly-quota")
+	if len(name) == 0 || len(scopes) == 0 || days < 0 || rateLimit < 0 || dailyQuota < 0 || monthlyQuota < 0 {
+		respondAndExit("Args Error", name, scopes, days, rateLimit, dailyQuota, monthlyQuota)
+	}
+	reqBody := ApiKeyData{Name: name, Scopes: scopes, RateLimit: rateLimit, DailyQuota: dailyQuota, MonthlyQuota: monthlyQuota}
+	if days > 0 {
+		reqBody.ExpiresAt = time.Now().AddDate(0, 0, days).Unix()
+	}
+	var apiKeyData ApiKey
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "keys"
+	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
+	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
+	if res.StatusCode != http.StatusOK {
+		respondWithApiError(res)
+	}
+	json.NewDecoder(res.Body).Decode(&apiKeyData)
+	consoleApiKeyWriter(apiKeyData)
+	return nil
+}
+
+func listApiKeys(cCtx *cli.Context) error {
+	var apiKeyDataList []ApiKey
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "keys"
+	res := apiService(http.MethodGet, endPoint, nil)
+	if res.StatusCode != http.StatusOK {
+		respondWithApiError(res)
+	}
+	json.NewDecoder(res.Body).Decode(&apiKeyDataList)
+	consoleApiKeyListWriter(apiKeyDataList)
+	return nil
+}
+
+func revokeApiKey(cCtx *cli.Context) error {
+	keyId := cCtx.Args().Get(0)
+	if len(keyId) == 0 {
+		respondAndExit("Args Error", keyId)
+	}
+	var apiKeyData ApiKey
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "keys/" + url.PathEscape(keyId)
+	res := apiService(http.MethodDelete, endPoint, nil)
+	if res.StatusCode != http.StatusOK {
+		respondWithApiError(res)
+	}
+	json.NewDecoder(res.Body).Decode(&apiKeyData)
+	consoleApiKeyWriter(apiKeyData)
+	return nil
+}
+
+func getAuditLogs(cCtx *cli.Context) error {
+	timeFrame := ((cCtx.Int("days") * 24) + cCtx.Int("hours")) * -1
+	page := max(cCtx.Int("page"), 0)
+	reqBody := AuditQuery{Start: time.Now().Add(time.Duration(timeFrame) * time.Hour).Unix(), End: time.Now().Unix(), Path: cCtx.String("path"), Actor: cCtx.String("actor")}
+	var auditLogList []AuditLog
+	endPoint := httpsProtocol + apiHost + redirectorApiEndpoint + "audit?page=" + strconv.Itoa(page)
+	reqBodyBytes := bytes.NewBuffer(toJson(reqBody))
+	res := apiService(http.MethodPost, endPoint, reqBodyBytes)
+	if res.StatusCode != http.StatusOK {
+		respondWithApiError(res)
+	}
+	json.NewDecoder(res.Body).Decode(&auditLogList)
+	consoleAuditListWriter(auditLogList)
+	return nil
+}
@@ -8,6 +8,16 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+var pageFlags = []cli.Flag{
+	&cli.IntFlag{Name: "limit", Aliases: []string{"l"}, Usage: "page size (max 100)"},
+	&cli.StringFlag{Name: "sort", Aliases: []string{"s"}, Usage: "id, path, updatedAt or createdAt (prefix - for descending)"},
+	&cli.StringFlag{Name: "status", Usage: "active, inactive or all"},
+	&cli.StringFlag{Name: "tag", Aliases: []string{"t"}, Usage: "only redirects with this tag"},
+	&cli.StringFlag{Name: "domain", Aliases: []string{"d"}, Usage: "only redirects to this domain"},
+	&cli.StringFlag{Name: "cursor", Aliases: []string{"C"}, Usage: "cursor from a previous page"},
+	&cli.BoolFlag{Name: "all", Aliases: []string{"a"}, Usage: "follow cursors to fetch every page"},
+}
+
 func main() {
 	app := &cli.App{
 		Name:                 "redirector",
@@ -43,10 +53,17 @@ func main() {
 				Action:             getUrlRedirect,
 			},
 			{
-				Name:               "create",
-				Usage:              "create an new redirect",
-				Args:               true,
-				ArgsUsage:          "path url",
+				Name:      "create",
+				Usage:     "create an new redirect",
+				Args:      true,
+				ArgsUsage: "path url",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "allow-duplicate", Usage: "create another redirect for an existing url"},
+					&cli.StringSliceFlag{Name: "tag", Aliases: []string{"t"}},
+					&cli.IntFlag{Name: "status-code", Aliases: []string{"c"}, Value: 0, Usage: "301, 302, 303, 307 or 308"},
+					&cli.StringFlag{Name: "title", Aliases: []string{"T"}, Usage: "human readable title"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "validate and show what would change without writing"},
+				},
 				HideHelpCommand:    true,
 				CustomHelpTemplate: commandHelpText,
 				Action:             addUrlRedirect,
@@ -58,6 +75,11 @@ func main() {
 				ArgsUsage: "path url",
 				Flags: []cli.Flag{
 					&cli.IntFlag{Name: "id", Aliases: []string{"I"}, Value: 0},
+					&cli.BoolFlag{Name: "allow-duplicate", Usage: "point the redirect at a url another redirect already uses"},
+					&cli.StringSliceFlag{Name: "tag", Aliases: []string{"t"}},
+					&cli.IntFlag{Name: "status-code", Aliases: []string{"c"}, Value: 0, Usage: "301, 302, 303, 307 or 308"},
+					&cli.StringFlag{Name: "title", Aliases: []string{"T"}, Usage: "human readable title"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "validate and show what would change without writing"},
 				},
 				HideHelpCommand:    true,
 				CustomHelpTemplate: commandHelpText,
@@ -69,51 +91,158 @@ func main() {
 				Args:  false,
 				Flags: []cli.Flag{
 					&cli.IntFlag{Name: "id", Aliases: []string{"I"}, Value: 0},
+					&cli.BoolFlag{Name: "dry-run", Usage: "validate and show what would change without writing"},
 				},
 				CustomHelpTemplate: commandHelpText,
 				Action:             disableUrlRedirect,
 			},
 			{
-				Name:               "fix",
-				Usage:              "fix an existing redirect",
-				Args:               true,
-				ArgsUsage:          "path url",
+				Name:      "fix",
+				Usage:     "fix an existing redirect",
+				Args:      true,
+				ArgsUsage: "path url",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "allow-duplicate", Usage: "point the redirect at a url another redirect already uses"},
+					&cli.StringSliceFlag{Name: "tag", Aliases: []string{"t"}},
+					&cli.IntFlag{Name: "status-code", Aliases: []string{"c"}, Value: 0, Usage: "301, 302, 303, 307 or 308"},
+					&cli.StringFlag{Name: "title", Aliases: []string{"T"}, Usage: "human readable title"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "validate and show what would change without writing"},
+				},
 				HideHelpCommand:    true,
 				CustomHelpTemplate: commandHelpText,
 				Action:             fixUrlRedirect,
 			},
 			{
-				Name:            "list",
-				Usage:           "list all redirects",
-				Args:            false,
-				HideHelpCommand: true,
-				Flags: []cli.Flag{
-					&cli.IntFlag{Name: "page", Aliases: []string{"P"}, Value: 0},
-				},
+				Name:               "list",
+				Usage:              "list all redirects",
+				Args:               false,
+				HideHelpCommand:    true,
+				Flags:              pageFlags,
 				CustomHelpTemplate: commandHelpText,
 				Action:             listUrlRedirects,
 			},
 			{
-				Name:               "generate",
-				Usage:              "generates an short url redirect",
-				Args:               true,
-				ArgsUsage:          "url",
+				Name:      "generate",
+				Usage:     "generates an short url redirect",
+				Args:      true,
+				ArgsUsage: "url",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "strategy", Aliases: []string{"s"}, Usage: "random, unambiguous, sequential, hashid or words"},
+					&cli.IntFlag{Name: "length", Aliases: []string{"l"}, Value: 0, Usage: "path length or number of words"},
+					&cli.BoolFlag{Name: "reuse", Aliases: []string{"r"}, Usage: "return the existing redirect for the url"},
+					&cli.BoolFlag{Name: "allow-duplicate", Usage: "create another redirect for an existing url"},
+					&cli.StringSliceFlag{Name: "tag", Aliases: []string{"t"}},
+					&cli.IntFlag{Name: "status-code", Aliases: []string{"c"}, Value: 0, Usage: "301, 302, 303, 307 or 308"},
+					&cli.StringFlag{Name: "title", Aliases: []string{"T"}, Usage: "human readable title"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "validate and show what would change without writing"},
+				},
 				HideHelpCommand:    true,
 				CustomHelpTemplate: commandHelpText,
 				Action:             generateShortRedirect,
 			},
 			{
-				Name:            "search",
-				Usage:           "search for a redirect",
-				Args:            true,
-				ArgsUsage:       "search_text",
-				HideHelpCommand: true,
+				Name:      "import",
+				Usage:     "bulk import redirects from a csv, json, ndjson, bitly, yourls, kutt or apache file",
+				Args:      true,
+				ArgsUsage: "file",
 				Flags: []cli.Flag{
-					&cli.IntFlag{Name: "page", Aliases: []string{"P"}, Value: 0},
+					&cli.StringFlag{Name: "mode", Aliases: []string{"m"}, Value: "skip", Usage: "skip, update or atomic"},
+					&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Usage: "csv, json, ndjson, bitly, yourls, kutt or apache (default: from file extension)"},
+					&cli.BoolFlag{Name: "allow-duplicate", Usage: "create redirects for existing urls"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "preview the import and conflicts without writing"},
+				},
+				HideHelpCommand:    true,
+				CustomHelpTemplate: commandHelpText,
+				Action:             importUrlRedirects,
+			},
+			{
+				Name:      "batch",
+				Usage:     "disable, enable, tag, move, expire or change the status code of many redirects at once",
+				Args:      true,
+				ArgsUsage: "action [id...]",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "tag", Aliases: []string{"t"}, Usage: "tags to add for the tag action"},
+					&cli.StringFlag{Name: "folder", Aliases: []string{"f"}, Usage: "target folder for the move action"},
+					&cli.TimestampFlag{Name: "expires-at", Aliases: []string{"e"}, Layout: time.RFC3339, Usage: "expiry for the expire action (omit to clear)"},
+					&cli.IntFlag{Name: "status-code", Aliases: []string{"c"}, Value: 0, Usage: "301, 302, 303, 307 or 308"},
+					&cli.StringFlag{Name: "where-status", Usage: "select active, inactive or all redirects instead of ids"},
+					&cli.StringFlag{Name: "where-tag", Usage: "select redirects with this tag instead of ids"},
+					&cli.StringFlag{Name: "where-domain", Usage: "select redirects to this domain instead of ids"},
+					&cli.StringFlag{Name: "where-updated-since", Usage: "select redirects updated since a duration (72h), date (2006-01-02) or RFC3339 time instead of ids"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "preview the changes without writing"},
+				},
+				HideHelpCommand:    true,
+				CustomHelpTemplate: commandHelpText,
+				Action:             batchUrlRedirects,
+			},
+			{
+				Name:  "export",
+				Usage: "export all redirects as csv, json, ndjson or yaml",
+				Args:  false,
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "output file (default: stdout)"},
+					&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Usage: "csv, json, ndjson or yaml (default: from output extension)"},
+					&cli.StringFlag{Name: "status", Value: "all", Usage: "active, inactive or all"},
+					&cli.StringFlag{Name: "tag", Aliases: []string{"t"}},
+					&cli.StringFlag{Name: "since", Usage: "updated since a duration (72h), date (2006-01-02) or RFC3339 time"},
+				},
+				HideHelpCommand:    true,
+				CustomHelpTemplate: commandHelpText,
+				Action:             exportUrlRedirects,
+			},
+			{
+				Name:      "rules",
+				Usage:     "export redirects as web server rules",
+				Args:      true,
+				ArgsUsage: "nginx|apache|htaccess|netlify|caddy",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "output file (default: stdout)"},
+				},
+				HideHelpCommand:    true,
+				CustomHelpTemplate: commandHelpText,
+				Action:             exportUrlRedirectRules,
+			},
+			{
+				Name:  "static",
+				Usage: "export active redirects as a static html site",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: "static-site", Usage: "output directory, or a .zip file for the raw archive"},
 				},
+				HideHelpCommand:    true,
+				CustomHelpTemplate: commandHelpText,
+				Action:             exportStaticSite,
+			},
+			{
+				Name:               "search",
+				Usage:              "search for a redirect",
+				Args:               true,
+				ArgsUsage:          "search_text",
+				HideHelpCommand:    true,
+				Flags:              pageFlags,
 				CustomHelpTemplate: commandHelpText,
 				Action:             searchUrlRedirect,
 			},
+			{
+				Name:      "find",
+				Usage:     "ranked search across path, url, title and tags",
+				Args:      true,
+				ArgsUsage: "search_text",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "field", Aliases: []string{"f"}, Usage: "path, url, title or tags (default: all)"},
+					&cli.StringFlag{Name: "status", Usage: "active, inactive or all"},
+					&cli.StringSliceFlag{Name: "tag", Aliases: []string{"t"}, Usage: "only redirects with every tag"},
+					&cli.IntFlag{Name: "status-code", Aliases: []string{"c"}, Value: 0, Usage: "301, 302, 303, 307 or 308"},
+					&cli.TimestampFlag{Name: "created-after", Layout: time.RFC3339},
+					&cli.TimestampFlag{Name: "created-before", Layout: time.RFC3339},
+					&cli.TimestampFlag{Name: "updated-after", Layout: time.RFC3339},
+					&cli.TimestampFlag{Name: "updated-before", Layout: time.RFC3339},
+					&cli.IntFlag{Name: "limit", Aliases: []string{"l"}, Usage: "results per page (max 100)"},
+					&cli.IntFlag{Name: "offset", Aliases: []string{"o"}, Usage: "results to skip"},
+				},
+				HideHelpCommand:    true,
+				CustomHelpTemplate: commandHelpText,
+				Action:             findUrlRedirects,
+			},
 			{
 				Name:               "check",
 				Usage:              "check if a redirect exists",
@@ -135,6 +264,61 @@ func main() {
 				CustomHelpTemplate: commandHelpText,
 				Action:             getRedirectStats,
 			},
+			{
+				Name:            "audit",
+				Usage:           "get admin api audit log",
+				Args:            false,
+				HideHelpCommand: true,
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "days", Aliases: []string{"D"}, Value: 0},
+					&cli.IntFlag{Name: "hours", Aliases: []string{"H"}, Value: 24},
+					&cli.StringFlag{Name: "path", Aliases: []string{"p"}},
+					&cli.StringFlag{Name: "actor", Aliases: []string{"A"}},
+					&cli.IntFlag{Name: "page", Aliases: []string{"P"}, Value: 0},
+				},
+				CustomHelpTemplate: commandHelpText,
+				Action:             getAuditLogs,
+			},
+			{
+				Name:            "keys",
+				Usage:           "manage api keys",
+				HideHelpCommand: true,
+				Subcommands: []*cli.Command{
+					{
+						Name:      "create",
+						Usage:     "issue an new api key",
+						Args:      true,
+						ArgsUsage: "name",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "scope", Aliases: []string{"S"}, Usage: "read, write, stats or admin"},
+							&cli.IntFlag{Name: "days", Aliases: []string{"D"}, Value: 0, Usage: "expire after days"},
+							&cli.IntFlag{Name: "rate-limit", Aliases: []string{"R"}, Value: 0, Usage: "requests per second"},
+							&cli.IntFlag{Name: "daily-quota", Value: 0, Usage: "redirects created per day"},
+							&cli.IntFlag{Name: "monthly-quota", Value: 0, Usage: "redirects created per month"},
+						},
+						HideHelpCommand:    true,
+						CustomHelpTemplate: commandHelpText,
+						Action:             createApiKey,
+					},
+					{
+						Name:               "list",
+						Usage:              "list all api keys",
+						Args:               false,
+						HideHelpCommand:    true,
+						CustomHelpTemplate: commandHelpText,
+						Action:             listApiKeys,
+					},
+					{
+						Name:               "revoke",
+						Usage:              "revoke an api key",
+						Args:               true,
+						ArgsUsage:          "key_id",
+						HideHelpCommand:    true,
+						CustomHelpTemplate: commandHelpText,
+						Action:             revokeApiKey,
+					},
+				},
+			},
 		},
 		CustomAppHelpTemplate: appHelpText,
 	}
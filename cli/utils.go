@@ -1,14 +1,20 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 )
 
 var errorBytes []byte
@@ -51,28 +57,188 @@ type ResponseMessage struct {
 	Message string
 }
 
+type ApiError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Field     string `json:"field,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
 type Redirect struct {
-	Id          int    `json:"id,omitempty"`
-	Path        string `json:"path,omitempty"`
-	Url         string `json:"url,omitempty"`
-	LastUpdated string `json:"lastUpdated,omitempty"`
-	Inactive    bool   `json:"inactive,omitempty"`
+	Id             int      `json:"id,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	Url            string   `json:"url,omitempty"`
+	LastUpdated    string   `json:"lastUpdated,omitempty"`
+	Inactive       bool     `json:"inactive,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	StatusCode     int      `json:"statusCode,omitempty"`
+	Title          string   `json:"title,omitempty"`
+	CreatedAt      string   `json:"createdAt,omitempty"`
+	ImportedClicks int64    `json:"importedClicks,omitempty"`
+	Folder         string   `json:"folder,omitempty"`
+	ExpiresAt      string   `json:"expiresAt,omitempty"`
 }
 
 type UrlData struct {
-	Url  string `json:"url,omitempty"`
-	Path string `json:"path,omitempty"`
+	Url            string   `json:"url,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	AllowDuplicate bool     `json:"allowDuplicate,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	StatusCode     int      `json:"statusCode,omitempty"`
+	Title          string   `json:"title,omitempty"`
+}
+
+type SearchQuery struct {
+	Text          string     `json:"query,omitempty"`
+	Fields        []string   `json:"fields,omitempty"`
+	Inactive      *bool      `json:"inactive,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	StatusCode    int        `json:"statusCode,omitempty"`
+	CreatedAfter  *time.Time `json:"createdAfter,omitempty"`
+	CreatedBefore *time.Time `json:"createdBefore,omitempty"`
+	UpdatedAfter  *time.Time `json:"updatedAfter,omitempty"`
+	UpdatedBefore *time.Time `json:"updatedBefore,omitempty"`
+	Limit         int        `json:"limit,omitempty"`
+	Offset        int        `json:"offset,omitempty"`
+}
+
+type SearchMatch struct {
+	Redirect   Redirect          `json:"redirect"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type SearchResponse struct {
+	Total   int           `json:"total"`
+	Results []SearchMatch `json:"results"`
 }
 
 type OpsData struct {
 	Data string `json:"data,omitempty"`
 }
 
+type GenerateData struct {
+	Data           string   `json:"data,omitempty"`
+	Strategy       string   `json:"strategy,omitempty"`
+	Length         int      `json:"length,omitempty"`
+	Reuse          bool     `json:"reuse,omitempty"`
+	AllowDuplicate bool     `json:"allowDuplicate,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	StatusCode     int      `json:"statusCode,omitempty"`
+	Title          string   `json:"title,omitempty"`
+}
+
 type StatsTime struct {
 	Start int64 `json:"start,omitempty"`
 	End   int64 `json:"end,omitempty"`
 }
 
+type ApiKey struct {
+	Id           int      `json:"id,omitempty"`
+	KeyId        string   `json:"keyId,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	CreatedAt    string   `json:"createdAt,omitempty"`
+	ExpiresAt    string   `json:"expiresAt,omitempty"`
+	LastUsedAt   string   `json:"lastUsedAt,omitempty"`
+	Revoked      bool     `json:"revoked,omitempty"`
+	RateLimit    int      `json:"rateLimit,omitempty"`
+	DailyQuota   int      `json:"dailyQuota,omitempty"`
+	MonthlyQuota int      `json:"monthlyQuota,omitempty"`
+	Token        string   `json:"token,omitempty"`
+}
+
+type ApiKeyData struct {
+	Name         string   `json:"name,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	ExpiresAt    int64    `json:"expiresAt,omitempty"`
+	RateLimit    int      `json:"rateLimit,omitempty"`
+	DailyQuota   int      `json:"dailyQuota,omitempty"`
+	MonthlyQuota int      `json:"monthlyQuota,omitempty"`
+}
+
+type AuditLog struct {
+	Id           int64  `json:"id,omitempty"`
+	Timestamp    string `json:"timestamp,omitempty"`
+	Actor        string `json:"actor,omitempty"`
+	SourceIp     string `json:"sourceIp,omitempty"`
+	RequestId    string `json:"requestId,omitempty"`
+	Method       string `json:"method,omitempty"`
+	Action       string `json:"action,omitempty"`
+	RedirectId   int    `json:"redirectId,omitempty"`
+	RedirectPath string `json:"redirectPath,omitempty"`
+	Status       int    `json:"status,omitempty"`
+	Result       string `json:"result,omitempty"`
+}
+
+type AuditQuery struct {
+	Start int64  `json:"start,omitempty"`
+	End   int64  `json:"end,omitempty"`
+	Path  string `json:"path,omitempty"`
+	Actor string `json:"actor,omitempty"`
+}
+
+type ImportResult struct {
+	Row         int    `json:"row"`
+	Path        string `json:"path,omitempty"`
+	Id          int    `json:"id,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	Source      string `json:"source,omitempty"`
+	ExistingUrl string `json:"existingUrl,omitempty"`
+}
+
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type DryRunResult struct {
+	DryRun   bool                   `json:"dryRun"`
+	Outcome  string                 `json:"outcome"`
+	Redirect Redirect               `json:"redirect"`
+	Changes  map[string]FieldChange `json:"changes,omitempty"`
+}
+
+type BatchRequest struct {
+	Action     string   `json:"action"`
+	Ids        []int    `json:"ids,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Folder     string   `json:"folder,omitempty"`
+	ExpiresAt  int64    `json:"expiresAt,omitempty"`
+	StatusCode int      `json:"statusCode,omitempty"`
+}
+
+type BatchResult struct {
+	Id     int    `json:"id"`
+	Path   string `json:"path,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchReport struct {
+	Action     string        `json:"action"`
+	DryRun     bool          `json:"dryRun,omitempty"`
+	Total      int           `json:"total"`
+	Updated    int           `json:"updated"`
+	Unchanged  int           `json:"unchanged"`
+	Failed     int           `json:"failed"`
+	RolledBack bool          `json:"rolledBack,omitempty"`
+	Results    []BatchResult `json:"results,omitempty"`
+}
+
+type ImportReport struct {
+	Mode       string         `json:"mode"`
+	DryRun     bool           `json:"dryRun,omitempty"`
+	Total      int            `json:"total"`
+	Created    int            `json:"created"`
+	Updated    int            `json:"updated"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	RolledBack bool           `json:"rolledBack,omitempty"`
+	Results    []ImportResult `json:"results,omitempty"`
+}
+
 type LogStatsDataList struct {
 	Path   []LogStatsData `json:"path,omitempty"`
 	Status []LogStatsData `json:"status,omitempty"`
@@ -127,19 +293,108 @@ func consoleDataWriter(r Redirect) {
 	fmt.Fprintf(w, "ID:\t%d\n", r.Id)
 	fmt.Fprintf(w, "Path:\t%s\n", r.Path)
 	fmt.Fprintf(w, "URL:\t%s\n", absoluteUrl)
+	fmt.Fprintf(w, "Title:\t%s\n", r.Title)
 	fmt.Fprintf(w, "Inactive:\t%t\n", r.Inactive)
+	fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(r.Tags, ","))
+	fmt.Fprintf(w, "Status Code:\t%d\n", r.StatusCode)
+	fmt.Fprintf(w, "Created:\t%s\n", r.CreatedAt)
 	fmt.Fprintf(w, "Updated:\t%s\n", r.LastUpdated)
+	fmt.Fprintf(w, "Imported Clicks:\t%d\n", r.ImportedClicks)
+	fmt.Fprintf(w, "Folder:\t%s\n", r.Folder)
+	fmt.Fprintf(w, "Expires:\t%s\n", r.ExpiresAt)
 	w.Flush()
 	defer os.Exit(0)
 }
 
-func consoleDataListWriter(redirectList []Redirect) {
+func consoleDryRunWriter(result DryRunResult) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tPath\tUrl\tInactive")
-	fmt.Fprintln(w, "--\t----\t---\t--------")
+	fmt.Fprintf(w, "Dry Run:\t%s\n", result.Outcome)
+	fields := make([]string, 0, len(result.Changes))
+	for field := range result.Changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		fmt.Fprintf(w, "  %s:\t%v -> %v\n", field, result.Changes[field].Before, result.Changes[field].After)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+	consoleDataWriter(result.Redirect)
+}
+
+func consoleDataListWriter(redirectList []Redirect, totalCount string, nextCursor string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPath\tUrl\tInactive\tTags")
+	fmt.Fprintln(w, "--\t----\t---\t--------\t----")
 	for _, r := range redirectList {
-		fmt.Fprintf(w, "%d\t%s\t%s\t%t\n", r.Id, r.Path, r.Url, r.Inactive)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%s\n", r.Id, r.Path, r.Url, r.Inactive, strings.Join(r.Tags, ","))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Showing:\t%d of %s\n", len(redirectList), totalCount)
+	if len(nextCursor) > 0 {
+		fmt.Fprintf(w, "Next Cursor:\t%s\n", nextCursor)
+	}
+	w.Flush()
+	defer os.Exit(0)
+}
+
+func consoleApiKeyWriter(k ApiKey) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Key ID:\t%s\n", k.KeyId)
+	fmt.Fprintf(w, "Name:\t%s\n", k.Name)
+	fmt.Fprintf(w, "Scopes:\t%s\n", strings.Join(k.Scopes, ","))
+	fmt.Fprintf(w, "Created:\t%s\n", k.CreatedAt)
+	fmt.Fprintf(w, "Expires:\t%s\n", k.ExpiresAt)
+	fmt.Fprintf(w, "Last Used:\t%s\n", k.LastUsedAt)
+	fmt.Fprintf(w, "Revoked:\t%t\n", k.Revoked)
+	fmt.Fprintf(w, "Rate Limit:\t%d\n", k.RateLimit)
+	fmt.Fprintf(w, "Daily Quota:\t%d\n", k.DailyQuota)
+	fmt.Fprintf(w, "Monthly Quota:\t%d\n", k.MonthlyQuota)
+	if len(k.Token) > 0 {
+		fmt.Fprintf(w, "Token:\t%s\n", k.Token)
+	}
+	w.Flush()
+	defer os.Exit(0)
+}
+
+func consoleApiKeyListWriter(apiKeyList []ApiKey) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Key ID\tName\tScopes\tExpires\tLast Used\tRevoked")
+	fmt.Fprintln(w, "------\t----\t------\t-------\t---------\t-------")
+	for _, k := range apiKeyList {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", k.KeyId, k.Name, strings.Join(k.Scopes, ","), k.ExpiresAt, k.LastUsedAt, k.Revoked)
+	}
+	w.Flush()
+	defer os.Exit(0)
+}
+
+func consoleAuditListWriter(auditLogList []AuditLog) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Time\tActor\tSource IP\tMethod\tAction\tPath\tStatus\tResult\tRequest ID")
+	fmt.Fprintln(w, "----\t-----\t---------\t------\t------\t----\t------\t------\t----------")
+	for _, a := range auditLogList {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", a.Timestamp, a.Actor, a.SourceIp, a.Method, a.Action, a.RedirectPath, a.Status, a.Result, a.RequestId)
+	}
+	w.Flush()
+	defer os.Exit(0)
+}
+
+func consoleImportWriter(report ImportReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Row\tPath\tID\tStatus\tExisting URL\tError\tSource")
+	fmt.Fprintln(w, "---\t----\t--\t------\t------------\t-----\t------")
+	for _, r := range report.Results {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\n", r.Row, r.Path, r.Id, r.Status, r.ExistingUrl, r.Error, r.Source)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Mode:\t%s\n", report.Mode)
+	fmt.Fprintf(w, "Dry Run:\t%t\n", report.DryRun)
+	fmt.Fprintf(w, "Total:\t%d\n", report.Total)
+	fmt.Fprintf(w, "Created:\t%d\n", report.Created)
+	fmt.Fprintf(w, "Updated:\t%d\n", report.Updated)
+	fmt.Fprintf(w, "Skipped:\t%d\n", report.Skipped)
+	fmt.Fprintf(w, "Failed:\t%d\n", report.Failed)
+	fmt.Fprintf(w, "Rolled Back:\t%t\n", report.RolledBack)
 	w.Flush()
 	defer os.Exit(0)
 }
@@ -181,11 +436,89 @@ func consoleStatsWriter(statsData LogStatsDataList) {
 	defer os.Exit(0)
 }
 
+func writeOutput(outputPath string, data io.Reader) {
+	output := os.Stdout
+	if len(outputPath) > 0 && outputPath != "-" {
+		outputFile, fileErr := os.Create(outputPath)
+		if fileErr != nil {
+			respondAndExit("Args Error", fileErr)
+		}
+		defer outputFile.Close()
+		output = outputFile
+	}
+	if _, copyErr := io.Copy(output, data); copyErr != nil {
+		respondAndExit("Export Failed", copyErr)
+	}
+}
+
+func extractArchive(outputDir string, archiveData []byte) (int, error) {
+	archive, archiveErr := zip.NewReader(bytes.NewReader(archiveData), int64(len(archiveData)))
+	if archiveErr != nil {
+		return 0, archiveErr
+	}
+	for _, archiveFile := range archive.File {
+		fileName := filepath.FromSlash(archiveFile.Name)
+		if !filepath.IsLocal(fileName) {
+			return 0, fmt.Errorf("invalid archive entry %q", archiveFile.Name)
+		}
+		filePath := filepath.Join(outputDir, fileName)
+		if dirErr := os.MkdirAll(filepath.Dir(filePath), 0o755); dirErr != nil {
+			return 0, dirErr
+		}
+		archiveReader, openErr := archiveFile.Open()
+		if openErr != nil {
+			return 0, openErr
+		}
+		outputFile, fileErr := os.Create(filePath)
+		if fileErr != nil {
+			archiveReader.Close()
+			return 0, fileErr
+		}
+		_, copyErr := io.Copy(outputFile, archiveReader)
+		archiveReader.Close()
+		outputFile.Close()
+		if copyErr != nil {
+			return 0, copyErr
+		}
+	}
+	return len(archive.File), nil
+}
+
+func parseSince(since string) (int64, error) {
+	if sinceDuration, durationErr := time.ParseDuration(since); durationErr == nil {
+		return time.Now().Add(-sinceDuration).Unix(), nil
+	}
+	if sinceDate, dateErr := time.ParseInLocation(time.DateOnly, since, time.Local); dateErr == nil {
+		return sinceDate.Unix(), nil
+	}
+	sinceTime, timeErr := time.Parse(time.RFC3339, since)
+	return sinceTime.Unix(), timeErr
+}
+
 func respondAndExit(msg string, args ...any) {
 	fmt.Println(msg, args)
 	defer os.Exit(1)
 }
 
+func respondWithApiError(res *http.Response) {
+	var apiError ApiError
+	decodeErr := json.NewDecoder(res.Body).Decode(&apiError)
+	if decodeErr != nil || len(apiError.Code) == 0 {
+		respondAndExit(res.Status)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Status:\t%s\n", res.Status)
+	fmt.Fprintf(w, "Code:\t%s\n", apiError.Code)
+	fmt.Fprintf(w, "Message:\t%s\n", apiError.Message)
+	if len(apiError.Field) > 0 {
+		fmt.Fprintf(w, "Field:\t%s\n", apiError.Field)
+	}
+	fmt.Fprintf(w, "Request ID:\t%s\n", apiError.RequestId)
+	w.Flush()
+	defer os.Exit(1)
+}
+
 func toJson(struc any) []byte {
 	responseMessageJson, err := json.Marshal(struc)
 	if err != nil {
@@ -194,3 +527,42 @@ func toJson(struc any) []byte {
 		return responseMessageJson
 	}
 }
+
+func consoleSearchWriter(searchResponse SearchResponse, offset int) {
+	highlightReplacer := strings.NewReplacer("<em>", "[", "</em>", "]")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPath\tUrl\tScore\tMatches")
+	fmt.Fprintln(w, "--\t----\t---\t-----\t-------")
+	for _, match := range searchResponse.Results {
+		var highlights []string
+		for _, field := range []string{"path", "url", "title", "tags"} {
+			if highlight, isHighlighted := match.Highlights[field]; isHighlighted {
+				highlights = append(highlights, field+": "+html.UnescapeString(highlightReplacer.Replace(highlight)))
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.2f\t%s\n", match.Redirect.Id, match.Redirect.Path, match.Redirect.Url, match.Score, strings.Join(highlights, "; "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Showing:\t%d-%d of %d\n", min(offset+1, offset+len(searchResponse.Results)), offset+len(searchResponse.Results), searchResponse.Total)
+	w.Flush()
+	defer os.Exit(0)
+}
+
+func consoleBatchWriter(report BatchReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPath\tStatus\tError")
+	fmt.Fprintln(w, "--\t----\t------\t-----")
+	for _, r := range report.Results {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", r.Id, r.Path, r.Status, r.Error)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Action:\t%s\n", report.Action)
+	fmt.Fprintf(w, "Dry Run:\t%t\n", report.DryRun)
+	fmt.Fprintf(w, "Total:\t%d\n", report.Total)
+	fmt.Fprintf(w, "Updated:\t%d\n", report.Updated)
+	fmt.Fprintf(w, "Unchanged:\t%d\n", report.Unchanged)
+	fmt.Fprintf(w, "Failed:\t%d\n", report.Failed)
+	fmt.Fprintf(w, "Rolled Back:\t%t\n", report.RolledBack)
+	w.Flush()
+	defer os.Exit(0)
+}